@@ -0,0 +1,14 @@
+package gopev
+
+import "regexp"
+
+// ansiPattern matches the ANSI escape sequences a terminal interprets:
+// CSI sequences such as colors and cursor movement, and OSC sequences such
+// as hyperlinks, ended by BEL or ST.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// StripANSI removes colors and other escape sequences from already rendered
+// output, such as output captured from a terminal that is to be saved.
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
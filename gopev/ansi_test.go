@@ -0,0 +1,25 @@
+package gopev
+
+import (
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"\x1b[91m12.00 ms\x1b[0m", "12.00 ms"},
+		{"\x1b[97;41m slowest \x1b[0m", " slowest "},
+		{"\x1b[2K\x1b[1Aline", "line"},
+		{"\x1b]8;;https://example.com\x07link\x1b]8;;\x07", "link"},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"plain ○ text", "plain ○ text"},
+	}
+
+	for _, test := range tests {
+		if got := StripANSI(test.input); got != test.want {
+			t.Errorf("StripANSI(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package gopev
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteOutputAppendix lists each node's output columns keyed by node ID, for
+// use with Options.OutputAppendix which leaves them out of the tree.
+func WriteOutputAppendix(writer io.Writer, explain *Explain, options Options) {
+	fmt.Fprintf(writer, "%v Output:\n", Glyphs.Bullet)
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		output := NonEmptyOutput(plan)
+
+		if len(output) == 0 {
+			return
+		}
+
+		if options.Redact {
+			for index, column := range output {
+				output[index] = RedactLiterals(column)
+			}
+		}
+
+		label := fmt.Sprintf("#%d", plan.ID)
+		indent := strings.Repeat(" ", len(label))
+
+		for index, line := range strings.Split(wrapText(strings.Join(output, " + "), 60), "\n") {
+			if index == 0 {
+				fmt.Fprintf(writer, "  %v %v\n", MutedFormat(label), OutputFormat(line))
+			} else {
+				fmt.Fprintf(writer, "  %v %v\n", indent, OutputFormat(line))
+			}
+		}
+	})
+}
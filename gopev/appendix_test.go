@@ -0,0 +1,39 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteOutputAppendix(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Limit", "Output": ["t.id", "'x'::text"], "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "t", "Schema": "public", "Output": [""], "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}]},
+		"Execution Time": 1}]`)
+
+	var written strings.Builder
+	WriteOutputAppendix(&written, explain, Options{Redact: true})
+
+	if want := "○ Output:\n  #1 t.id + ?::text\n"; written.String() != want {
+		t.Errorf("WriteOutputAppendix = %q, want %q", written.String(), want)
+	}
+}
+
+func TestOutputAppendixOption(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	explain.Plan.Output = []string{"a.id"}
+
+	rendered := renderPlain(t, explain, Options{OutputAppendix: true})
+
+	if strings.Contains(rendered, "⌡►") {
+		t.Errorf("output columns left in the tree with OutputAppendix:\n%v", rendered)
+	}
+
+	if !strings.HasSuffix(rendered, "○ Output:\n  #1 a.id\n") {
+		t.Errorf("output does not end with the appendix:\n%v", rendered)
+	}
+}
@@ -0,0 +1,69 @@
+package gopev
+
+// CriticalPathTime estimates the query's wall time from node self-times. It
+// sums them like TotalDuration, except that the children of a Parallel or
+// async Append run side by side, so only the slowest of them counts.
+func CriticalPathTime(explain *Explain) float64 {
+	return criticalPathTime(&explain.Plan)
+}
+
+func criticalPathTime(plan *Plan) float64 {
+	var children float64
+
+	for index, _ := range plan.Plans {
+		child := criticalPathTime(&plan.Plans[index])
+
+		if RunsChildrenConcurrently(plan) {
+			if child > children {
+				children = child
+			}
+		} else {
+			children += child
+		}
+	}
+
+	return plan.ActualDuration + children
+}
+
+// RunsChildrenConcurrently reports whether a node's children execute at the
+// same time rather than one after another: those of a Parallel Append, and
+// those of an Append over async-capable foreign scans.
+func RunsChildrenConcurrently(plan *Plan) bool {
+	if plan.NodeType != Append && plan.NodeType != MergeAppend {
+		return false
+	}
+
+	if plan.ParallelAware {
+		return true
+	}
+
+	for _, child := range plan.Plans {
+		if child.AsyncCapable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DominantShare is the share of the execution time above which a single node
+// is called out as dominating the query.
+var DominantShare float64 = 0.8
+
+// DominantNode returns the node whose self-time is at least DominantShare of
+// the execution time, or nil when there is none or the plan has one node.
+func DominantNode(explain *Explain) *Plan {
+	if explain.EstimateOnly || len(explain.Plan.Plans) == 0 {
+		return nil
+	}
+
+	var dominant *Plan
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		if DurationShare(explain, plan) >= DominantShare && (dominant == nil || plan.ActualDuration > dominant.ActualDuration) {
+			dominant = plan
+		}
+	})
+
+	return dominant
+}
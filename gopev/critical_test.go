@@ -0,0 +1,126 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+const parallelAppend = `[{"Plan": {"Node Type": "Append", "Parallel Aware": true, "Total Cost": 300, "Plan Rows": 2000, "Actual Total Time": 30, "Actual Rows": 2000, "Actual Loops": 1,
+	"Plans": [
+		{"Node Type": "Seq Scan", "Parent Relationship": "Member", "Parallel Aware": true, "Relation Name": "orders_east", "Schema": "public", "Total Cost": 150, "Plan Rows": 1000, "Actual Total Time": 25, "Actual Rows": 1000, "Actual Loops": 1},
+		{"Node Type": "Seq Scan", "Parent Relationship": "Member", "Parallel Aware": true, "Relation Name": "orders_west", "Schema": "public", "Total Cost": 150, "Plan Rows": 1000, "Actual Total Time": 28, "Actual Rows": 1000, "Actual Loops": 1}]},
+	"Execution Time": 30.5}]`
+
+const serialAppend = `[{"Plan": {"Node Type": "Append", "Total Cost": 300, "Plan Rows": 2000, "Actual Total Time": 60, "Actual Rows": 2000, "Actual Loops": 1,
+	"Plans": [
+		{"Node Type": "Seq Scan", "Parent Relationship": "Member", "Relation Name": "orders_east", "Schema": "public", "Total Cost": 150, "Plan Rows": 1000, "Actual Total Time": 25, "Actual Rows": 1000, "Actual Loops": 1},
+		{"Node Type": "Seq Scan", "Parent Relationship": "Member", "Relation Name": "orders_west", "Schema": "public", "Total Cost": 150, "Plan Rows": 1000, "Actual Total Time": 28, "Actual Rows": 1000, "Actual Loops": 1}]},
+	"Execution Time": 60.5}]`
+
+const asyncAppend = `[{"Plan": {"Node Type": "Append", "Total Cost": 300, "Plan Rows": 2000, "Actual Total Time": 30, "Actual Rows": 2000, "Actual Loops": 1,
+	"Plans": [
+		{"Node Type": "Foreign Scan", "Parent Relationship": "Member", "Async Capable": true, "Relation Name": "orders_east", "Schema": "public", "Total Cost": 150, "Plan Rows": 1000, "Actual Total Time": 25, "Actual Rows": 1000, "Actual Loops": 1},
+		{"Node Type": "Foreign Scan", "Parent Relationship": "Member", "Async Capable": true, "Relation Name": "orders_west", "Schema": "public", "Total Cost": 150, "Plan Rows": 1000, "Actual Total Time": 28, "Actual Rows": 1000, "Actual Loops": 1}]},
+	"Execution Time": 30.5}]`
+
+func analyzeAppend(t *testing.T, buffer string) *Explain {
+	t.Helper()
+
+	explains, err := Analyze([]byte(buffer))
+
+	if err != nil || len(explains) != 1 {
+		t.Fatalf("Analyze = %d plans, %v", len(explains), err)
+	}
+
+	return &explains[0]
+}
+
+func TestCalculateActualsConcurrentChildren(t *testing.T) {
+	explain := analyzeAppend(t, parallelAppend)
+
+	if got := explain.Plan.ActualDuration; got != 2 {
+		t.Errorf("Parallel Append self-time = %v, want 30 - 28 = 2", got)
+	}
+
+	explain = analyzeAppend(t, serialAppend)
+
+	if got := explain.Plan.ActualDuration; got != 7 {
+		t.Errorf("serial Append self-time = %v, want 60 - 25 - 28 = 7", got)
+	}
+}
+
+func TestCriticalPathTime(t *testing.T) {
+	explain := analyzeAppend(t, parallelAppend)
+
+	if got := CriticalPathTime(explain); got != 30 {
+		t.Errorf("CriticalPathTime = %v, want 30", got)
+	}
+
+	if got := explain.TotalDuration; got != 55 {
+		t.Errorf("TotalDuration = %v, want 55", got)
+	}
+
+	if got := CriticalPathTime(analyzeAppend(t, serialAppend)); got != 60 {
+		t.Errorf("serial CriticalPathTime = %v, want 60", got)
+	}
+}
+
+func TestRunsChildrenConcurrently(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want bool
+	}{
+		{Plan{NodeType: Append, ParallelAware: true}, true},
+		{Plan{NodeType: MergeAppend, ParallelAware: true}, true},
+		{Plan{NodeType: Append, Plans: []Plan{{AsyncCapable: true}}}, true},
+		{Plan{NodeType: Append, Plans: []Plan{{NodeType: SequenceScan}}}, false},
+		{Plan{NodeType: NestedLoop, ParallelAware: true}, false},
+	}
+
+	for _, test := range tests {
+		if got := RunsChildrenConcurrently(&test.plan); got != test.want {
+			t.Errorf("RunsChildrenConcurrently(%+v) = %v, want %v", test.plan, got, test.want)
+		}
+	}
+}
+
+func TestAsyncForeignScanDetail(t *testing.T) {
+	explain := analyzeOne(t, asyncAppend)
+
+	if got := explain.Plan.ActualDuration; got != 2 {
+		t.Errorf("async Append self-time = %v, want 30 - 28 = 2", got)
+	}
+
+	rendered := renderPlain(t, explain, Options{})
+
+	if strings.Count(rendered, "Foreign Scan [Async]") != 2 {
+		t.Errorf("output does not mark both foreign scans async:\n%v", rendered)
+	}
+}
+
+func TestDominantNode(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	if dominant := DominantNode(explain); dominant == nil || dominant.ID != 1 {
+		t.Errorf("DominantNode = %v, want #1", dominant)
+	}
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "○ #1 Nested Loop takes 95% of the execution time\n") {
+		t.Errorf("output does not call out the dominant node:\n%v", rendered)
+	}
+
+	share := DominantShare
+	defer func() { DominantShare = share }()
+
+	DominantShare = 0.99
+
+	if dominant := DominantNode(explain); dominant != nil {
+		t.Errorf("DominantNode above a 99%% share = #%d, want none", dominant.ID)
+	}
+
+	single := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	if dominant := DominantNode(single); dominant != nil {
+		t.Errorf("DominantNode of a single-node plan = #%d, want none", dominant.ID)
+	}
+}
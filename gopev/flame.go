@@ -0,0 +1,132 @@
+package gopev
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// flameBar is one node's span in a row of the flame graph, in characters.
+type flameBar struct {
+	plan       *Plan
+	start, end int
+}
+
+// VisualizeFlame draws each plan in buffer as a text flame graph width
+// characters wide. Every node is a bar spanning its own and its
+// descendants' self-time, with its children stacked on top of it, so the
+// part of a bar left uncovered is proportional to the node's self-time.
+// Nodes too brief to get a character are left out.
+func VisualizeFlame(writer io.Writer, buffer []byte, width int) error {
+	if width < 1 {
+		return errors.New("flame graph width must be positive")
+	}
+
+	explains, err := Analyze(buffer)
+
+	if err != nil {
+		return err
+	}
+
+	for index, _ := range explains {
+		explain := &explains[index]
+
+		if explain.EstimateOnly || explain.TimingOff {
+			_, err = fmt.Fprintf(writer, "%v\n", MutedFormat("no timings to draw a flame graph from"))
+		} else {
+			err = writeFlame(writer, explain, width)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeFlame(writer io.Writer, explain *Explain, width int) error {
+	inclusive := map[*Plan]float64{}
+	var Inclusive func(plan *Plan) float64
+
+	Inclusive = func(plan *Plan) float64 {
+		time := math.Max(plan.ActualDuration, 0)
+		for index, _ := range plan.Plans {
+			time += Inclusive(&plan.Plans[index])
+		}
+		inclusive[plan] = time
+		return time
+	}
+
+	total := Inclusive(&explain.Plan)
+
+	if total <= 0 {
+		_, err := fmt.Fprintf(writer, "%v\n", MutedFormat("no timings to draw a flame graph from"))
+		return err
+	}
+
+	position := func(time float64) int {
+		return int(math.Round(time / total * float64(width)))
+	}
+
+	var rows [][]flameBar
+	var Place func(plan *Plan, left float64, depth int)
+
+	Place = func(plan *Plan, left float64, depth int) {
+		start, end := position(left), position(left+inclusive[plan])
+
+		if end <= start {
+			return
+		}
+
+		for len(rows) <= depth {
+			rows = append(rows, nil)
+		}
+		rows[depth] = append(rows[depth], flameBar{plan, start, end})
+
+		for index, _ := range plan.Plans {
+			child := &plan.Plans[index]
+			Place(child, left, depth+1)
+			left += inclusive[child]
+		}
+	}
+
+	Place(&explain.Plan, 0, 0)
+
+	for depth := len(rows) - 1; depth >= 0; depth-- {
+		bars := rows[depth]
+		sort.Slice(bars, func(i, j int) bool { return bars[i].start < bars[j].start })
+
+		var line strings.Builder
+		column := 0
+
+		for _, bar := range bars {
+			line.WriteString(strings.Repeat(" ", bar.start-column))
+			line.WriteString(SeverityFormat(NodeSeverity(explain, bar.plan))(flameLabel(bar.plan, bar.end-bar.start)))
+			column = bar.end
+		}
+
+		_, err := fmt.Fprintf(writer, "%v\n", line.String())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flameLabel names a node within a bar width characters wide, filling the
+// rest of the bar.
+func flameLabel(plan *Plan, width int) string {
+	label := []rune(fmt.Sprintf("▕#%d %v %.2f ms", plan.ID, plan.NodeType, plan.ActualDuration))
+
+	if len(label) > width {
+		label = label[:width]
+	}
+
+	return string(label) + strings.Repeat("▁", width-len(label))
+}
@@ -0,0 +1,78 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestVisualizeFlame(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	var written strings.Builder
+
+	if err := VisualizeFlame(&written, []byte(nestedLoopFunctionScan), 100); err != nil {
+		t.Fatalf("VisualizeFlame: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(written.String(), "\n"), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("VisualizeFlame drew %d rows, want 2:\n%v", len(lines), written.String())
+	}
+
+	// The children are stacked above the root, the Seq Scan first.
+	if !strings.HasPrefix(lines[0], "▕#") || !strings.Contains(lines[0], "▕#3 Function Scan 470.00 ms") {
+		t.Errorf("top row = %q, want the Seq Scan and the Function Scan", lines[0])
+	}
+
+	if utf8.RuneCountInString(lines[0]) != 51 {
+		t.Errorf("top row is %d wide, want 51", utf8.RuneCountInString(lines[0]))
+	}
+
+	if !strings.HasPrefix(lines[1], "▕#1 Nested Loop 475.30 ms▁") || utf8.RuneCountInString(lines[1]) != 100 {
+		t.Errorf("root row = %q, want the Nested Loop spanning 100", lines[1])
+	}
+}
+
+func TestVisualizeFlameWithoutTimings(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	var written strings.Builder
+
+	if err := VisualizeFlame(&written, []byte(`[{"Plan": {"Node Type": "Result", "Total Cost": 0.01, "Plan Rows": 1}}]`), 40); err != nil {
+		t.Fatalf("VisualizeFlame: %v", err)
+	}
+
+	if written.String() != "no timings to draw a flame graph from\n" {
+		t.Errorf("VisualizeFlame of an estimate-only plan = %q", written.String())
+	}
+
+	if err := VisualizeFlame(&written, []byte(nestedLoopFunctionScan), 0); err == nil {
+		t.Errorf("VisualizeFlame accepted a width of 0")
+	}
+}
+
+func TestFlameLabel(t *testing.T) {
+	plan := &Plan{ID: 2, NodeType: SequenceScan, ActualDuration: 1.5}
+
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{25, "▕#2 Seq Scan 1.50 ms▁▁▁▁▁"},
+		{6, "▕#2 Se"},
+	}
+
+	for _, test := range tests {
+		if got := flameLabel(plan, test.width); got != test.want {
+			t.Errorf("flameLabel(%d) = %q, want %q", test.width, got, test.want)
+		}
+	}
+}
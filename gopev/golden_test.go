@@ -0,0 +1,78 @@
+package gopev
+
+import (
+	"bytes"
+	"flag"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenOptions are the options a plan in testdata is rendered with, for
+// the plans kept there to cover an option rather than a plan shape.
+var goldenOptions = map[string]Options{
+	"hidedescriptions": {HideDescriptions: true},
+}
+
+// TestGolden renders every plan in testdata with VisualizeWithOptions, using
+// its goldenOptions if it has any, and compares the output with the .golden
+// file next to it. Run go test -update to record the output after an
+// intended change, and review the diff.
+func TestGolden(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	color.NoColor = true
+
+	plans, err := filepath.Glob("testdata/*.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plans) == 0 {
+		t.Fatal("no plans in testdata")
+	}
+
+	for _, plan := range plans {
+		name := strings.TrimSuffix(filepath.Base(plan), ".json")
+
+		t.Run(name, func(t *testing.T) {
+			buffer, err := os.ReadFile(plan)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var rendered bytes.Buffer
+
+			if err := VisualizeWithOptions(&rendered, buffer, goldenOptions[name]); err != nil {
+				t.Fatalf("VisualizeWithOptions: %v", err)
+			}
+
+			golden := strings.TrimSuffix(plan, ".json") + ".golden"
+
+			if *update {
+				if err := os.WriteFile(golden, rendered.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+
+			if err != nil {
+				t.Fatalf("%v, run go test -update to create it", err)
+			}
+
+			if !bytes.Equal(rendered.Bytes(), want) {
+				t.Errorf("output differs from %v, run go test -update and review the diff:\n%v", golden, rendered.String())
+			}
+		})
+	}
+}
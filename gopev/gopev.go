@@ -3,11 +3,12 @@ package gopev
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/dustin/go-humanize"
-	"github.com/fatih/color"
-	"github.com/mitchellh/go-wordwrap"
 	"io"
+	"math"
+	"regexp"
 	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 type EstimateDirection string
@@ -20,50 +21,142 @@ const (
 type NodeType string
 
 const (
-	Limit           NodeType = "Limit"
-	Append                   = "Append"
-	Sort                     = "Sort"
-	NestedLoop               = "Nested Loop"
-	MergeJoin                = "Merge Join"
-	Hash                     = "Hash"
-	HashJoin                 = "Hash Join"
-	Aggregate                = "Aggregate"
-	Hashaggregate            = "Hashaggregate"
-	SequenceScan             = "Seq Scan"
-	IndexScan                = "Index Scan"
-	IndexOnlyScan            = "Index Only Scan"
-	BitmapHeapScan           = "Bitmap Heap Scan"
-	BitmapIndexScan          = "Bitmap Index Scan"
-	CTEScan                  = "CTE Scan"
+	Limit               NodeType = "Limit"
+	Append                       = "Append"
+	Sort                         = "Sort"
+	NestedLoop                   = "Nested Loop"
+	MergeJoin                    = "Merge Join"
+	Hash                         = "Hash"
+	HashJoin                     = "Hash Join"
+	Aggregate                    = "Aggregate"
+	Hashaggregate                = "Hashaggregate"
+	SequenceScan                 = "Seq Scan"
+	IndexScan                    = "Index Scan"
+	IndexOnlyScan                = "Index Only Scan"
+	BitmapHeapScan               = "Bitmap Heap Scan"
+	BitmapIndexScan              = "Bitmap Index Scan"
+	CTEScan                      = "CTE Scan"
+	Materialize                  = "Materialize"
+	IncrementalSort              = "Incremental Sort"
+	NamedTuplestoreScan          = "Named Tuplestore Scan"
+	SampleScan                   = "Sample Scan"
+	ModifyTable                  = "ModifyTable"
+	ForeignScan                  = "Foreign Scan"
+	MergeAppend                  = "Merge Append"
+	WindowAgg                    = "WindowAgg"
+	Gather                       = "Gather"
+	GatherMerge                  = "Gather Merge"
+	Memoize                      = "Memoize"
 )
 
-var PrefixFormat = color.New(color.FgHiBlack).SprintFunc()
-var TagFormat = color.New(color.FgWhite, color.BgRed).SprintFunc()
-var MutedFormat = color.New(color.FgHiBlack).SprintFunc()
-var BoldFormat = color.New(color.FgHiWhite).SprintFunc()
-var GoodFormat = color.New(color.FgGreen).SprintFunc()
-var WarningFormat = color.New(color.FgHiYellow).SprintFunc()
-var CriticalFormat = color.New(color.FgHiRed).SprintFunc()
-var OutputFormat = color.New(color.FgCyan).SprintFunc()
+var PrefixFormat = DarkTheme.Prefix
+var TagFormat = DarkTheme.Tag
+var MutedFormat = DarkTheme.Muted
+var BoldFormat = DarkTheme.Bold
+var GoodFormat = DarkTheme.Good
+var WarningFormat = DarkTheme.Warning
+var CriticalFormat = DarkTheme.Critical
+var OutputFormat = DarkTheme.Output
 
 // LabelFormat := color.New(color.FgWhite, color.BgBlue).SprintfFunc()
 
 var Descriptions = map[NodeType]string{
-	Append:          "Used in a UNION to merge multiple record sets by appending them together.",
-	Limit:           "Returns a specified number of rows from a record set.",
-	Sort:            "Sorts a record set based on the specified sort key.",
-	NestedLoop:      "Merges two record sets by looping through every record in the first set and trying to find a match in the second set. All matching records are returned.",
-	MergeJoin:       "Merges two record sets by first sorting them on a join key.",
-	Hash:            "Generates a hash table from the records in the input recordset. Hash is used by Hash Join.",
-	HashJoin:        "Joins to record sets by hashing one of them (using a Hash Scan).",
-	Aggregate:       "Groups records together based on a GROUP BY or aggregate function (e.g. sum()).",
-	Hashaggregate:   "Groups records together based on a GROUP BY or aggregate function (e.g. sum()). Hash Aggregate uses a hash to first organize the records by a key.",
-	SequenceScan:    "Finds relevant records by sequentially scanning the input record set. When reading from a table, Seq Scans (unlike Index Scans) perform a single read operation (only the table is read).",
-	IndexScan:       "Finds relevant records based on an Index. Index Scans perform 2 read operations: one to read the index and another to read the actual value from the table.",
-	IndexOnlyScan:   "Finds relevant records based on an Index. Index Only Scans perform a single read operation from the index and do not read from the corresponding table.",
-	BitmapHeapScan:  "Searches through the pages returned by the Bitmap Index Scan for relevant rows.",
-	BitmapIndexScan: "Uses a Bitmap Index (index which uses 1 bit per page) to find all relevant pages. Results of this node are fed to the Bitmap Heap Scan.",
-	CTEScan:         "Performs a sequential scan of Common Table Expression (CTE) query results. Note that results of a CTE are materialized (calculated and temporarily stored).",
+	Append:              "Used in a UNION to merge multiple record sets by appending them together.",
+	MergeAppend:         "Merges the sorted record sets of its children, keeping the combined output sorted. Used for UNION ALL and partitioned tables.",
+	Limit:               "Returns a specified number of rows from a record set.",
+	Sort:                "Sorts a record set based on the specified sort key.",
+	IncrementalSort:     "Sorts a record set that is already sorted on a prefix of the sort key, sorting each group of equal prefix values separately.",
+	NestedLoop:          "Merges two record sets by looping through every record in the first set and trying to find a match in the second set. All matching records are returned.",
+	MergeJoin:           "Merges two record sets by first sorting them on a join key.",
+	Hash:                "Generates a hash table from the records in the input recordset. Hash is used by Hash Join.",
+	HashJoin:            "Joins to record sets by hashing one of them (using a Hash Scan).",
+	Aggregate:           "Groups records together based on a GROUP BY or aggregate function (e.g. sum()).",
+	Hashaggregate:       "Groups records together based on a GROUP BY or aggregate function (e.g. sum()). Hash Aggregate uses a hash to first organize the records by a key.",
+	SequenceScan:        "Finds relevant records by sequentially scanning the input record set. When reading from a table, Seq Scans (unlike Index Scans) perform a single read operation (only the table is read).",
+	IndexScan:           "Finds relevant records based on an Index. Index Scans perform 2 read operations: one to read the index and another to read the actual value from the table.",
+	IndexOnlyScan:       "Finds relevant records based on an Index. Index Only Scans perform a single read operation from the index and do not read from the corresponding table.",
+	BitmapHeapScan:      "Searches through the pages returned by the Bitmap Index Scan for relevant rows.",
+	BitmapIndexScan:     "Uses a Bitmap Index (index which uses 1 bit per page) to find all relevant pages. Results of this node are fed to the Bitmap Heap Scan.",
+	CTEScan:             "Performs a sequential scan of Common Table Expression (CTE) query results. Note that results of a CTE are materialized (calculated and temporarily stored).",
+	SampleScan:          "Reads a random sample of a table's pages or rows, as requested by TABLESAMPLE.",
+	NamedTuplestoreScan: "Scans a named tuplestore, such as the transition table (OLD TABLE / NEW TABLE) of an AFTER trigger.",
+	Gather:              "Collects the records produced by parallel workers running the plan below it, in no particular order.",
+	GatherMerge:         "Collects the sorted records produced by parallel workers running the plan below it, preserving their order.",
+	WindowAgg:           "Computes window functions (e.g. row_number() OVER (...)) over partitions of a sorted record set.",
+	ForeignScan:         "Reads records from a foreign table through its foreign data wrapper, e.g. by sending a query to a remote server with postgres_fdw.",
+	ModifyTable:         "Inserts, updates or deletes the records produced by its input. With ON CONFLICT, rows that violate a unique index are skipped or updated instead.",
+	Memoize:             "Caches the records of its input by the values of the cache key, so repeated lookups with the same key, typically from the inner side of a nested loop, are answered without running its input again.",
+	Materialize:         "Stores the records of its input in memory (spilling to disk if needed) so they can be read repeatedly, typically by the inner side of a join.",
+}
+
+// DefaultMaxDepth is how deeply a plan may be nested before it is rejected,
+// unless MaxDepth or Options.MaxDepth say otherwise.
+const DefaultMaxDepth = 1000
+
+// MaxDepth is the nesting limit for entry points that take no Options, such
+// as Analyze, Render and VisualizeFlame, and for Options with a MaxDepth of
+// 0. Negative limits are rejected.
+var MaxDepth = DefaultMaxDepth
+
+// DefaultIndentWidth is the number of columns each level of the tree is
+// indented by when Options.IndentWidth is not set.
+const DefaultIndentWidth = 2
+
+// Styles for Options.Style. StyleTree, also used when Style is empty, draws
+// the tree with box-drawing connectors; StyleIndent only indents each level
+// by two spaces.
+const (
+	StyleTree   = "tree"
+	StyleIndent = "indent"
+)
+
+type Options struct {
+	ShowCumulative     bool
+	CumulativeFilter   bool
+	ShowPlannerCost    bool
+	Redact             bool
+	CollapsePartitions bool
+	ShowLegend         bool
+	ShowTimeline       bool
+	MaxLines           int
+	HideDescriptions   bool
+	MaxDepth           int
+	MaxConditionLength int
+	TopNodes           int
+	OutputAppendix     bool
+	HideZeroMetrics    bool
+	Theme              *Theme
+	Numbers            *NumberFormat
+	Symbols            *Symbols
+	Style              string
+	WorkMemKB          uint64
+	HashMemMultiplier  float64
+	ShowEstimates      bool
+	ShowCostRank       bool
+	IndentWidth        int
+
+	// Triage keeps only the detail lines that matter most when chasing a
+	// slow query: join types, conditions and filters, row estimates, hints
+	// and annotations, with relations named without their schema and node
+	// descriptions left out.
+	Triage bool
+
+	// WarningsOnly draws only the branches with a node that has a warning,
+	// folding each branch without any into a single line.
+	WarningsOnly bool
+
+	// CastHint and SerialPlanHint add the hints of the same names, which are
+	// heuristics left out of Hints.
+	CastHint       bool
+	SerialPlanHint bool
+
+	// Annotations are comments, such as a reviewer's, shown under the node
+	// with the matching ID.
+	Annotations map[int]string
+
+	// warnedBranches are the nodes with a warning on them or below them,
+	// found once per render for WarningsOnly.
+	warnedBranches map[*Plan]bool
 }
 
 type Explain struct {
@@ -71,59 +164,120 @@ type Explain struct {
 	PlanningTime  float64       `json:"Planning Time"`
 	Triggers      []interface{} `json:"Triggers"`
 	ExecutionTime float64       `json:"Execution Time"`
-	TotalCost     float64
-	MaxRows       uint64
-	MaxCost       float64
-	MaxDuration   float64
+
+	// Derived by ProcessExplain. TotalCost and TotalDuration are the sums of
+	// every node's own cost and self-time; the Max fields are the largest
+	// ActualRows (PlanRows for estimate-only plans), ActualCost and
+	// ActualDuration of any single node. Self-times cover all of a node's
+	// loops while costs are for one, so TotalLoopedCost sums the own costs
+	// scaled by EffectiveLoops to put them on the footing of TotalDuration.
+	TotalCost       float64
+	TotalLoopedCost float64
+	TotalDuration   float64
+	MaxRows         uint64
+	MaxCost         float64
+	MaxDuration     float64
+	EstimateOnly    bool
+	Generic         bool
+	TimingOff       bool
 }
 
 type Plan struct {
 	ActualCost                  float64
 	ActualDuration              float64
-	ActualLoops                 uint64  `json:"Actual Loops"`
-	ActualRows                  uint64  `json:"Actual Rows"`
-	ActualStartupTime           float64 `json:"Actual Startup Time"`
-	ActualTotalTime             float64 `json:"Actual Total Time"`
-	Alias                       string  `json:"Alias"`
+	ActualLoops                 uint64   `json:"Actual Loops"`
+	ActualRows                  uint64   `json:"Actual Rows"`
+	ActualStartupTime           float64  `json:"Actual Startup Time"`
+	ActualTotalTime             float64  `json:"Actual Total Time"`
+	Alias                       string   `json:"Alias"`
+	AsyncCapable                bool     `json:"Async Capable"`
+	CacheEvictions              uint64   `json:"Cache Evictions"`
+	CacheHits                   uint64   `json:"Cache Hits"`
+	CacheKey                    string   `json:"Cache Key"`
+	CacheMisses                 uint64   `json:"Cache Misses"`
+	CacheOverflows              uint64   `json:"Cache Overflows"`
+	ConflictArbiterIndexes      []string `json:"Conflict Arbiter Indexes"`
+	ConflictFilter              string   `json:"Conflict Filter"`
+	ConflictResolution          string   `json:"Conflict Resolution"`
+	ConflictingTuples           uint64   `json:"Conflicting Tuples"`
 	Costliest                   bool
-	CTEName                     string   `json:"CTE Name"`
-	Filter                      string   `json:"Filter"`
-	GroupKey                    []string `json:"Group Key"`
-	HashCondition               string   `json:"Hash Cond"`
-	HeapFetches                 uint64   `json:"Heap Fetches"`
-	IndexCondition              string   `json:"Index Cond"`
-	IndexName                   string   `json:"Index Name"`
-	IOReadTime                  float64  `json:"I/O Read Time"`
-	IOWriteTime                 float64  `json:"I/O Write Time"`
-	JoinType                    string   `json:"Join Type"`
+	CTEName                     string `json:"CTE Name"`
+	CumulativeRowsRemoved       uint64
+	DiskUsage                   uint64      `json:"Disk Usage"`
+	Filter                      string      `json:"Filter"`
+	FullSortGroups              *SortGroups `json:"Full-sort Groups"`
+	GatherWorkersLaunched       uint64
+	GroupKey                    []string      `json:"Group Key"`
+	GroupingSets                []GroupingSet `json:"Grouping Sets"`
+	HashAggBatches              uint64        `json:"HashAgg Batches"`
+	HashBatches                 uint64        `json:"Hash Batches"`
+	HashCondition               string        `json:"Hash Cond"`
+	HeapFetches                 uint64        `json:"Heap Fetches"`
+	ID                          int
+	IndexCondition              string  `json:"Index Cond"`
+	IndexName                   string  `json:"Index Name"`
+	IOReadTime                  float64 `json:"I/O Read Time"`
+	IOWriteTime                 float64 `json:"I/O Write Time"`
+	JoinFilter                  string  `json:"Join Filter"`
+	JoinSide                    string
+	JoinType                    string `json:"Join Type"`
 	Largest                     bool
-	LocalDirtiedBlocks          uint64   `json:"Local Dirtied Blocks"`
-	LocalHitBlocks              uint64   `json:"Local Hit Blocks"`
-	LocalReadBlocks             uint64   `json:"Local Read Blocks"`
-	LocalWrittenBlocks          uint64   `json:"Local Written Blocks"`
+	LocalDirtiedBlocks          uint64 `json:"Local Dirtied Blocks"`
+	LocalHitBlocks              uint64 `json:"Local Hit Blocks"`
+	LocalReadBlocks             uint64 `json:"Local Read Blocks"`
+	LocalWrittenBlocks          uint64 `json:"Local Written Blocks"`
+	LoopCost                    float64
+	MaximumStorage              uint64   `json:"Maximum Storage"`
+	MergeCondition              string   `json:"Merge Cond"`
 	NodeType                    NodeType `json:"Node Type"`
+	Operation                   string   `json:"Operation"`
 	Output                      []string `json:"Output"`
-	ParentRelationship          string   `json:"Parent Relationship"`
+	Parallel                    bool
+	ParallelAware               bool   `json:"Parallel Aware"`
+	ParentRelationship          string `json:"Parent Relationship"`
 	PlannerRowEstimateDirection EstimateDirection
 	PlannerRowEstimateFactor    float64
-	PlanRows                    uint64 `json:"Plan Rows"`
-	PlanWidth                   uint64 `json:"Plan Width"`
-	RelationName                string `json:"Relation Name"`
-	RowsRemovedByFilter         uint64 `json:"Rows Removed by Filter"`
-	RowsRemovedByIndexRecheck   uint64 `json:"Rows Removed by Index Recheck"`
-	ScanDirection               string `json:"Scan Direction"`
-	Schema                      string `json:"Schema"`
-	SharedDirtiedBlocks         uint64 `json:"Shared Dirtied Blocks"`
-	SharedHitBlocks             uint64 `json:"Shared Hit Blocks"`
-	SharedReadBlocks            uint64 `json:"Shared Read Blocks"`
-	SharedWrittenBlocks         uint64 `json:"Shared Written Blocks"`
+	CostEstimateDirection       EstimateDirection
+	CostEstimateFactor          float64
+	PeakMemoryUsage             uint64 `json:"Peak Memory Usage"`
+	CostRank                    int
+	PlanRows                    uint64      `json:"Plan Rows"`
+	PlanWidth                   uint64      `json:"Plan Width"`
+	PreSortedGroups             *SortGroups `json:"Pre-sorted Groups"`
+	PresortedKey                []string    `json:"Presorted Key"`
+	RecheckCondition            string      `json:"Recheck Cond"`
+	RelationName                string      `json:"Relation Name"`
+	RepeatableSeed              string      `json:"Repeatable Seed"`
+	RowsRemovedByFilter         uint64      `json:"Rows Removed by Filter"`
+	RowsRemovedByIndexRecheck   uint64      `json:"Rows Removed by Index Recheck"`
+	RowsRemovedByJoinFilter     uint64      `json:"Rows Removed by Join Filter"`
+	Runs                        *RunStats
+	SamplingMethod              string   `json:"Sampling Method"`
+	SamplingParameters          []string `json:"Sampling Parameters"`
+	ScanDirection               string   `json:"Scan Direction"`
+	Schema                      string   `json:"Schema"`
+	SharedDirtiedBlocks         uint64   `json:"Shared Dirtied Blocks"`
+	SharedHitBlocks             uint64   `json:"Shared Hit Blocks"`
+	SharedReadBlocks            uint64   `json:"Shared Read Blocks"`
+	SharedWrittenBlocks         uint64   `json:"Shared Written Blocks"`
+	SingleCopy                  bool     `json:"Single Copy"`
 	Slowest                     bool
-	StartupCost                 float64 `json:"Startup Cost"`
-	Strategy                    string  `json:"Strategy"`
-	TempReadBlocks              uint64  `json:"Temp Read Blocks"`
-	TempWrittenBlocks           uint64  `json:"Temp Written Blocks"`
-	TotalCost                   float64 `json:"Total Cost"`
-	Plans                       []Plan  `json:"Plans"`
+	SortKey                     []string `json:"Sort Key"`
+	SortMethod                  string   `json:"Sort Method"`
+	SortSpaceType               string   `json:"Sort Space Type"`
+	SortSpaceUsed               uint64   `json:"Sort Space Used"`
+	StartupCost                 float64  `json:"Startup Cost"`
+	Storage                     string   `json:"Storage"`
+	Strategy                    string   `json:"Strategy"`
+	SubplanName                 string   `json:"Subplan Name"`
+	TempReadBlocks              uint64   `json:"Temp Read Blocks"`
+	TempWrittenBlocks           uint64   `json:"Temp Written Blocks"`
+	TuplestoreName              string   `json:"Tuplestore Name"`
+	TuplesInserted              uint64   `json:"Tuples Inserted"`
+	TotalCost                   float64  `json:"Total Cost"`
+	WorkersLaunched             uint64   `json:"Workers Launched"`
+	WorkersPlanned              uint64   `json:"Workers Planned"`
+	Plans                       []Plan   `json:"Plans"`
 }
 
 func CalculatePlannerEstimate(explain *Explain, plan *Plan) {
@@ -142,39 +296,117 @@ func CalculatePlannerEstimate(explain *Explain, plan *Plan) {
 	}
 }
 
+// CalculateActuals sets a node's self-time and own cost by taking its
+// children's off its totals. The children of a node that runs them at the
+// same time (see RunsChildrenConcurrently) overlap, so their times add up to
+// more than the node's own; only the slowest is taken off, which would
+// otherwise leave the node with a negative self-time.
 func CalculateActuals(explain *Explain, plan *Plan) {
 	plan.ActualDuration = plan.ActualTotalTime
 	plan.ActualCost = plan.TotalCost
 
+	var slowestChild float64
+
 	for _, child := range plan.Plans {
 		if child.NodeType != CTEScan {
-			plan.ActualDuration = plan.ActualDuration - child.ActualTotalTime
+			if RunsChildrenConcurrently(plan) {
+				slowestChild = math.Max(slowestChild, child.ActualTotalTime)
+			} else {
+				plan.ActualDuration = plan.ActualDuration - child.ActualTotalTime
+			}
 			plan.ActualCost = plan.ActualCost - child.TotalCost
 		}
 	}
 
+	plan.ActualDuration = plan.ActualDuration - slowestChild
+
 	if plan.ActualCost < 0 {
 		plan.ActualCost = 0
 	}
 
 	explain.TotalCost = explain.TotalCost + plan.ActualCost
+	explain.TotalLoopedCost = explain.TotalLoopedCost + plan.ActualCost*EffectiveLoops(plan)
+
+	plan.ActualDuration = plan.ActualDuration * EffectiveLoops(plan)
+
+	explain.TotalDuration = explain.TotalDuration + plan.ActualDuration
+}
+
+// EffectiveLoops is how many times a node ran one after another. Below a
+// Gather the loops are spread over the workers and the leader running at the
+// same time, so only each process's share of them adds to the elapsed time.
+func EffectiveLoops(plan *Plan) float64 {
+	loops := float64(plan.ActualLoops)
+
+	if plan.Parallel {
+		loops = loops / float64(plan.GatherWorkersLaunched+1)
+
+		if loops < 1 && plan.ActualLoops > 0 {
+			loops = 1
+		}
+	}
+
+	return loops
+}
+
+// CalculateCostEstimate compares a node's time per unit of cost against the
+// plan average; Under means the node ran longer than its cost implied. The
+// cost is for a single loop while the duration covers all of them, so the
+// cost is scaled by EffectiveLoops first, as it is in the plan's
+// TotalLoopedCost the average is taken from. Nodes taking less than
+// SlowForCostShare of the plan's time are left at a factor of 0.
+func CalculateCostEstimate(explain *Explain, plan *Plan) {
+	plan.CostEstimateFactor = 0
+	plan.CostEstimateDirection = Under
+
+	cost := plan.ActualCost * EffectiveLoops(plan)
+
+	if cost == 0 || explain.TotalLoopedCost == 0 || explain.TotalDuration == 0 {
+		return
+	}
+
+	if plan.ActualDuration < explain.TotalDuration*SlowForCostShare {
+		return
+	}
+
+	average := explain.TotalDuration / explain.TotalLoopedCost
 
-	plan.ActualDuration = plan.ActualDuration * float64(plan.ActualLoops)
+	plan.CostEstimateFactor = (plan.ActualDuration / cost) / average
+
+	if plan.CostEstimateFactor < 1.0 {
+		plan.CostEstimateDirection = Over
+		plan.CostEstimateFactor = 0
+		if plan.ActualDuration != 0 {
+			plan.CostEstimateFactor = (cost * average) / plan.ActualDuration
+		}
+	}
 }
 
 func CalculateOutlierNodes(explain *Explain, plan *Plan) {
-	plan.Costliest = plan.ActualCost == explain.MaxCost
-	plan.Largest = plan.ActualRows == explain.MaxRows
-	plan.Slowest = plan.ActualDuration == explain.MaxDuration
+	plan.Costliest = explain.MaxCost > 0 && plan.ActualCost == explain.MaxCost
+	plan.Largest = explain.MaxRows > 0 && OutlierRows(explain, plan) == explain.MaxRows
+	plan.Slowest = explain.MaxDuration > 0 && plan.ActualDuration == explain.MaxDuration
+
+	CalculateCostEstimate(explain, plan)
 
 	for index, _ := range plan.Plans {
 		CalculateOutlierNodes(explain, &plan.Plans[index])
 	}
 }
 
+// OutlierRows is the row count used to find the largest node: the actual
+// rows, or the planner's estimate when the plan has no actuals.
+func OutlierRows(explain *Explain, plan *Plan) uint64 {
+	if explain.EstimateOnly {
+		return plan.PlanRows
+	}
+
+	return plan.ActualRows
+}
+
 func CalculateMaximums(explain *Explain, plan *Plan) {
-	if explain.MaxRows < plan.ActualRows {
-		explain.MaxRows = plan.ActualRows
+	if rows := OutlierRows(explain, plan); explain.MaxRows < rows {
+		explain.MaxRows = rows
 	}
 	if explain.MaxCost < plan.ActualCost {
 		explain.MaxCost = plan.ActualCost
@@ -184,52 +416,524 @@ func CalculateMaximums(explain *Explain, plan *Plan) {
 	}
 }
 
+// CostShare is the fraction of the plan's total cost spent in this node.
+func CostShare(explain *Explain, plan *Plan) float64 {
+	if explain.TotalCost == 0 {
+		return 0
+	}
+
+	return plan.ActualCost / explain.TotalCost
+}
+
+// DurationShare is the fraction of the execution time spent in this node.
+func DurationShare(explain *Explain, plan *Plan) float64 {
+	if explain.ExecutionTime == 0 {
+		return 0
+	}
+
+	return plan.ActualDuration / explain.ExecutionTime
+}
+
+// CostSharePercentage adds up CostShare over every node below plan, as a
+// percentage. For a processed Explain it should come to ~100.
+func CostSharePercentage(explain *Explain, plan *Plan) float64 {
+	total := CostShare(explain, plan) * 100
+
+	for index, _ := range plan.Plans {
+		total = total + CostSharePercentage(explain, &plan.Plans[index])
+	}
+
+	return total
+}
+
+// DurationSeverity buckets a duration in milliseconds the way
+// DurationToString colors it.
+func DurationSeverity(value float64) Severity {
+	if value < 100 {
+		return SeverityGood
+	} else if value < 1000 {
+		return SeverityWarning
+	} else {
+		return SeverityCritical
+	}
+}
+
+func SeverityFormat(severity Severity) Format {
+	switch severity {
+	case SeverityCritical:
+		return CriticalFormat
+	case SeverityWarning:
+		return WarningFormat
+	default:
+		return GoodFormat
+	}
+}
+
 func DurationToString(value float64) string {
+	format := SeverityFormat(DurationSeverity(value))
+
 	if value < 1 {
-		return GoodFormat("<1 ms")
-	} else if value < 100 {
-		return GoodFormat(fmt.Sprintf("%.2f ms", value))
+		return format("<1 ms")
 	} else if value < 1000 {
-		return WarningFormat(fmt.Sprintf("%.2f ms", value))
+		return format(fmt.Sprintf("%.2f ms", value))
 	} else if value < 60000 {
-		return CriticalFormat(fmt.Sprintf("%.2f s", value/2000.0))
+		return format(fmt.Sprintf("%.2f s", value/1000.0))
 	} else {
-		return CriticalFormat(fmt.Sprintf("%.2f m", value/60000.0))
+		return format(fmt.Sprintf("%.2f m", value/60000.0))
 	}
 }
 
+// ProcessExplain fills in the derived fields (actuals, estimates, maximums
+// and outlier flags) of an Explain. It must run before WriteExplain, and
+// starts from scratch each time so running it again gives the same result.
 func ProcessExplain(explain *Explain) {
+	explain.TotalCost = 0
+	explain.TotalLoopedCost = 0
+	explain.TotalDuration = 0
+	explain.MaxRows = 0
+	explain.MaxCost = 0
+	explain.MaxDuration = 0
+
+	AssignIDs(explain)
+	CalculatePlanKind(explain)
+	explain.Plan.CumulativeRowsRemoved = explain.Plan.RowsRemovedByFilter
 	ProcessPlan(explain, &explain.Plan)
 	CalculateOutlierNodes(explain, &explain.Plan)
+	CalculateCostRanks(explain)
+}
+
+// AssignIDs numbers the nodes from 1 in pre-order, so ids are stable for a
+// given plan shape.
+func AssignIDs(explain *Explain) {
+	id := 0
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		id++
+		plan.ID = id
+	})
+}
+
+// NodeByID returns the node of a processed Explain with the given id, or nil
+// if there is none. As ids are assigned in pre-order, it only descends into
+// the last child numbered at or below id.
+func NodeByID(explain *Explain, id int) *Plan {
+	plan := &explain.Plan
+
+	if id < plan.ID {
+		return nil
+	}
+
+	for plan.ID != id {
+		var next *Plan
+
+		for index, _ := range plan.Plans {
+			if plan.Plans[index].ID <= id {
+				next = &plan.Plans[index]
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		plan = next
+	}
+
+	return plan
+}
+
+var parameterPattern = regexp.MustCompile(`\$\d+`)
+
+// CalculatePlanKind flags plans without ANALYZE actuals as estimate-only, and
+// those referring to parameter placeholders (EXPLAIN (GENERIC_PLAN)) as
+// generic. Parameters returned by an InitPlan or SubPlan, named in its
+// Subplan Name, do not count.
+func CalculatePlanKind(explain *Explain) {
+	explain.EstimateOnly = true
+	explain.Generic = false
+
+	returned := map[string]bool{}
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		for _, parameter := range parameterPattern.FindAllString(plan.SubplanName, -1) {
+			returned[parameter] = true
+		}
+	})
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		if plan.ActualLoops > 0 {
+			explain.EstimateOnly = false
+		}
+
+		for _, condition := range []string{plan.IndexCondition, plan.Filter, plan.HashCondition, plan.MergeCondition, plan.JoinFilter} {
+			for _, parameter := range parameterPattern.FindAllString(condition, -1) {
+				if !returned[parameter] {
+					explain.Generic = true
+				}
+			}
+		}
+	})
+
+	explain.Generic = explain.Generic && explain.EstimateOnly
+	explain.TimingOff = !explain.EstimateOnly && !HasTimings(&explain.Plan)
+}
+
+// HasTimings reports whether any node carries actual times, which it does
+// not for EXPLAIN (ANALYZE, TIMING OFF).
+func HasTimings(plan *Plan) bool {
+	timed := false
+
+	CollectPlans(plan, func(plan *Plan, path []*Plan) {
+		if plan.ActualStartupTime > 0 || plan.ActualTotalTime > 0 {
+			timed = true
+		}
+	})
+
+	return timed
 }
 
 func ProcessPlan(explain *Explain, plan *Plan) {
+	NormalizeNodeType(plan)
 	CalculatePlannerEstimate(explain, plan)
 	CalculateActuals(explain, plan)
 	CalculateMaximums(explain, plan)
+	PropagateParallelism(plan)
+	CalculateJoinSides(plan)
+	CalculateLoopCosts(explain, plan)
+	PropagateRowsRemoved(plan)
 
 	for index, _ := range plan.Plans {
 		ProcessPlan(explain, &plan.Plans[index])
 	}
 }
 
-func WriteExplain(writer io.Writer, explain *Explain) {
-	fmt.Fprintf(writer, "○ Total Cost: %s\n", humanize.Commaf(explain.TotalCost))
-	fmt.Fprintf(writer, "○ Planning Time: %s\n", DurationToString(explain.PlanningTime))
-	fmt.Fprintf(writer, "○ Execution Time: %s\n", DurationToString(explain.ExecutionTime))
-	fmt.Fprintf(writer, PrefixFormat("┬\n"))
+// NormalizeNodeType strips the "Parallel " prefix that some sources put on
+// node types (e.g. "Parallel Bitmap Heap Scan"), marking the node parallel
+// aware instead, so every parallel variant maps onto its base node type.
+// "Result Cache", the name Memoize had in PostgreSQL 14 betas, becomes
+// Memoize.
+func NormalizeNodeType(plan *Plan) {
+	if strings.HasPrefix(string(plan.NodeType), "Parallel ") {
+		plan.NodeType = NodeType(strings.TrimPrefix(string(plan.NodeType), "Parallel "))
+		plan.ParallelAware = true
+	}
+
+	if plan.NodeType == "Result Cache" {
+		plan.NodeType = Memoize
+	}
+}
+
+// PropagateParallelism marks the children of a Gather, and everything below
+// them, as running in parallel with the number of workers the Gather
+// launched.
+func PropagateParallelism(plan *Plan) {
+	for index, _ := range plan.Plans {
+		child := &plan.Plans[index]
+
+		if plan.NodeType == Gather || plan.NodeType == GatherMerge {
+			child.Parallel = true
+			child.GatherWorkersLaunched = plan.WorkersLaunched
+		} else if plan.Parallel {
+			child.Parallel = true
+			child.GatherWorkersLaunched = plan.GatherWorkersLaunched
+		}
+	}
+}
+
+// PropagateRowsRemoved accumulates the rows removed by filters from the root
+// down to each node.
+func PropagateRowsRemoved(plan *Plan) {
+	for index, _ := range plan.Plans {
+		child := &plan.Plans[index]
+		child.CumulativeRowsRemoved = plan.CumulativeRowsRemoved + child.RowsRemovedByFilter
+	}
+}
+
+// CalculateJoinSides labels the children of a join as its outer (driving)
+// or inner side.
+func CalculateJoinSides(plan *Plan) {
+	if plan.JoinType == "" {
+		return
+	}
+
+	for index, _ := range plan.Plans {
+		switch plan.Plans[index].ParentRelationship {
+		case "Outer":
+			plan.Plans[index].JoinSide = "outer"
+		case "Inner":
+			plan.Plans[index].JoinSide = "inner"
+		}
+	}
+}
+
+// CalculateLoopCosts sets LoopCost on the inner side of a nested loop, which
+// the planner costs per loop, to its cost times the number of times it ran:
+// its actual loops, or the rows expected from the outer side if the plan was
+// not executed.
+func CalculateLoopCosts(explain *Explain, plan *Plan) {
+	if plan.NodeType != NestedLoop || len(plan.Plans) != 2 {
+		return
+	}
+
+	outer, inner := &plan.Plans[0], &plan.Plans[1]
+
+	if inner.ParentRelationship != "Inner" {
+		outer, inner = inner, outer
+	}
+
+	loops := inner.ActualLoops
+	if explain.EstimateOnly {
+		loops = outer.PlanRows
+	}
+
+	if loops > 1 {
+		inner.LoopCost = inner.TotalCost * float64(loops)
+	}
+}
+
+// renderLock serializes WriteExplain, which swaps the package's formats,
+// glyphs and number formatters for those in its Options while it runs, with
+// the Apply methods that replace them.
+var renderLock sync.Mutex
+
+// WriteExplain renders an Explain that has already been through
+// ProcessExplain. It stops at and returns the first error from writer. The
+// Theme, Symbols and Numbers in options apply to this call only.
+func WriteExplain(writer io.Writer, explain *Explain, options Options) (err error) {
+	if err := checkDepth(&explain.Plan, options.MaxDepth); err != nil {
+		return err
+	}
+
+	renderLock.Lock()
+	defer renderLock.Unlock()
+
+	if options.Theme != nil {
+		previous := currentTheme()
+		defer previous.apply()
+
+		options.Theme.apply()
+	}
+
+	if options.Symbols != nil {
+		previous := Glyphs
+		defer previous.apply()
+
+		options.Symbols.apply()
+	}
+
+	if options.Numbers != nil {
+		integer, float, bytes := FormatInteger, FormatFloat, FormatBytes
+		defer func() {
+			FormatInteger, FormatFloat, FormatBytes = integer, float, bytes
+		}()
+
+		options.Numbers.apply()
+	}
+
+	output := &errorWriter{writer: writer}
+	writer = output
+
+	if options.MaxLines > 0 {
+		limiter := &lineLimiter{writer: writer, limit: options.MaxLines}
+		writer = limiter
+
+		defer func() {
+			limiter.Close()
+			err = output.err
+		}()
+	}
+
+	if explain.Generic {
+		fmt.Fprintf(writer, "%v %s\n", Glyphs.Bullet, WarningFormat("Generic Plan (estimates only)"))
+	} else if explain.EstimateOnly {
+		fmt.Fprintf(writer, "%v %s\n", Glyphs.Bullet, WarningFormat("Estimates Only"))
+	} else if explain.TimingOff {
+		fmt.Fprintf(writer, "%v %s\n", Glyphs.Bullet, WarningFormat("Timing Off (rows and loops only)"))
+	}
+
+	fmt.Fprintf(writer, "%v Total Cost: %s\n", Glyphs.Bullet, FormatCost(explain.TotalCost))
+	fmt.Fprintf(writer, "%v Planning Time: %s\n", Glyphs.Bullet, DurationToString(explain.PlanningTime))
+
+	if !explain.EstimateOnly {
+		fmt.Fprintf(writer, "%v Execution Time: %s\n", Glyphs.Bullet, DurationToString(explain.ExecutionTime))
+	}
+
+	if read, write := TotalIOTime(explain); read+write > 0 {
+		detail := ""
+		if explain.ExecutionTime > 0 {
+			detail = fmt.Sprintf(" (%.0f%%)", (read+write)/explain.ExecutionTime*100)
+		}
+		if read > 0 && write > 0 {
+			detail += fmt.Sprintf(" %s %s %s %s", MutedFormat("read"), DurationToString(read), MutedFormat("write"), DurationToString(write))
+		} else if write > 0 {
+			detail += " " + MutedFormat("writing")
+		}
+		fmt.Fprintf(writer, "%v I/O Time: %s%s\n", Glyphs.Bullet, DurationToString(read+write), detail)
+	}
+
+	if dominant := DominantNode(explain); dominant != nil {
+		node := fmt.Sprintf("#%d %v", dominant.ID, dominant.NodeType)
+		if dominant.RelationName != "" {
+			node += fmt.Sprintf(" on %v.%v", dominant.Schema, dominant.RelationName)
+		}
+		fmt.Fprintf(writer, "%v %s\n", Glyphs.Bullet, CriticalFormat(fmt.Sprintf("%v takes %.0f%% of the execution time", node, DurationShare(explain, dominant)*100)))
+	}
+	if options.Style != StyleIndent {
+		fmt.Fprint(writer, PrefixFormat(Glyphs.Root+"\n"))
+	}
+
+	if err := WritePlan(writer, explain, &explain.Plan, options, "", 0, len(explain.Plan.Plans) == 1); err != nil {
+		return err
+	}
+
+	writeFooter(writer, explain, options)
+
+	if options.OutputAppendix {
+		WriteOutputAppendix(writer, explain, options)
+	}
+
+	if options.ShowLegend {
+		WriteLegend(writer)
+	}
+
+	if options.TopNodes > 0 {
+		WriteTopNodes(writer, explain, options.TopNodes)
+	}
+
+	return output.err
+}
+
+// FilterRemovalRatio is the fraction of the rows read by a node that its
+// filter threw away.
+func FilterRemovalRatio(plan *Plan) float64 {
+	total := plan.ActualRows + plan.RowsRemovedByFilter
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(plan.RowsRemovedByFilter) / float64(total)
+}
+
+// StartupCostShare is the fraction of a node's total cost that must be spent
+// before its first row for the startup/run split to be shown.
+var StartupCostShare float64 = 0.5
+
+// HasSignificantStartupCost reports whether most of a node's cost is spent
+// up front, as with blocking nodes such as Sort and Hash.
+func HasSignificantStartupCost(plan *Plan) bool {
+	return plan.TotalCost > 0 && plan.StartupCost <= plan.TotalCost && plan.StartupCost/plan.TotalCost >= StartupCostShare
+}
+
+// TimelineBar draws when a node was active over the query's execution, from
+// its startup time to its total time, as a bar width characters wide.
+func TimelineBar(explain *Explain, plan *Plan, width int) string {
+	if explain.ExecutionTime <= 0 {
+		return "[" + strings.Repeat(" ", width) + "]"
+	}
+
+	position := func(time float64) int {
+		offset := int(math.Round(time / explain.ExecutionTime * float64(width)))
+		if offset < 0 {
+			return 0
+		} else if offset > width {
+			return width
+		}
+		return offset
+	}
+
+	start, end := position(plan.ActualStartupTime), position(plan.ActualTotalTime)
+
+	if end <= start {
+		if start == width {
+			start = width - 1
+		}
+		end = start + 1
+	}
+
+	return "[" + strings.Repeat(" ", start) + strings.Repeat("█", end-start) + strings.Repeat(" ", width-end) + "]"
+}
 
-	WritePlan(writer, explain, &explain.Plan, "", 0, len(explain.Plan.Plans) == 1)
+// TruncateCondition shortens a condition to limit characters, noting its
+// original length. A limit of 0 leaves it untouched.
+func TruncateCondition(condition string, limit int) string {
+	runes := []rune(condition)
+
+	if limit <= 0 || len(runes) <= limit {
+		return condition
+	}
+
+	return string(runes[:limit]) + "…" + MutedFormat(fmt.Sprintf(" (%v chars)", FormatInteger(int64(len(runes)))))
+}
+
+func WriteFooter(writer io.Writer, explain *Explain) {
+	writeFooter(writer, explain, Options{})
+}
+
+func writeFooter(writer io.Writer, explain *Explain, options Options) {
+	nodes, concurrent := 0, false
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		nodes++
+		concurrent = concurrent || RunsChildrenConcurrently(plan)
+	})
+
+	warnings := len(collectWarnings(explain, options))
+
+	var totals []string
+
+	if !explain.EstimateOnly {
+		totals = append(totals, DurationToString(explain.ExecutionTime))
+
+		if concurrent {
+			totals = append(totals, fmt.Sprintf("critical path %v", DurationToString(CriticalPathTime(explain))))
+		}
+	}
+
+	totals = append(totals,
+		fmt.Sprintf("cost %v", FormatCost(explain.TotalCost)),
+		fmt.Sprintf("%v nodes", nodes),
+		fmt.Sprintf("peak %v rows", FormatInteger(int64(explain.MaxRows))),
+	)
+
+	if warnings == 1 {
+		totals = append(totals, WarningFormat("1 warning"))
+	} else if warnings > 0 {
+		totals = append(totals, WarningFormat(fmt.Sprintf("%v warnings", warnings)))
+	} else {
+		totals = append(totals, GoodFormat("no warnings"))
+	}
+
+	score := planScore(explain, options)
+	totals = append(totals, ScoreFormat(score)(fmt.Sprintf("score %v", score)))
+
+	fmt.Fprintf(writer, "%v %v\n", Glyphs.Bullet, strings.Join(totals, MutedFormat(" · ")))
 }
 
 func FormatDetails(plan *Plan) string {
 	var details []string
 
+	if plan.Operation != "" {
+		details = append(details, plan.Operation)
+	}
+
+	if plan.ParallelAware {
+		details = append(details, "Parallel")
+	}
+
+	if plan.SingleCopy {
+		details = append(details, "Single Copy")
+	}
+
+	if plan.NodeType == ForeignScan && plan.AsyncCapable {
+		details = append(details, "Async")
+	}
+
 	if plan.ScanDirection != "" {
 		details = append(details, plan.ScanDirection)
 	}
 
-	if plan.Strategy != "" {
+	if plan.Strategy != "" && plan.NodeType != Aggregate {
 		details = append(details, plan.Strategy)
 	}
 
@@ -240,139 +944,598 @@ func FormatDetails(plan *Plan) string {
 	return ""
 }
 
+func FormatJoinSide(plan *Plan) string {
+	if plan.JoinSide != "" {
+		return MutedFormat(plan.JoinSide) + " "
+	}
+
+	return ""
+}
+
+// EstimateFormat picks a color for a node by how far off the planner's row
+// estimate was, so that estimate problems stand out across the whole tree.
+func EstimateFormat(plan *Plan) Format {
+	if plan.PlannerRowEstimateFactor == 0 {
+		return BoldFormat
+	} else if IsBadEstimate(plan) {
+		return CriticalFormat
+	} else if plan.PlannerRowEstimateFactor < 10 {
+		return GoodFormat
+	} else {
+		return WarningFormat
+	}
+}
+
 func FormatTag(tag string) string {
 	return TagFormat(fmt.Sprintf(" %v ", tag))
 }
 
-func FormatTags(plan *Plan) string {
+// PlanTags lists the plain text of the tags shown next to a node's type.
+func PlanTags(plan *Plan) []string {
 	var tags []string
 
 	if plan.Slowest {
-		tags = append(tags, FormatTag("slowest"))
+		tags = append(tags, "slowest")
 	}
 	if plan.Costliest {
-		tags = append(tags, FormatTag("costliest"))
+		tags = append(tags, "costliest")
 	}
 	if plan.Largest {
-		tags = append(tags, FormatTag("largest"))
+		tags = append(tags, "largest")
+	}
+	for _, warning := range tagWarnings(plan) {
+		if isTagged(warning.Kind) {
+			tags = append(tags, string(warning.Kind))
+		}
 	}
-	if plan.PlannerRowEstimateFactor >= 100 {
-		tags = append(tags, FormatTag("bad estimate"))
+
+	return tags
+}
+
+func FormatTags(plan *Plan) string {
+	var tags []string
+
+	for _, tag := range PlanTags(plan) {
+		tags = append(tags, FormatTag(tag))
 	}
 
 	return strings.Join(tags, " ")
 }
 
+func NonEmptyOutput(plan *Plan) []string {
+	var output []string
+
+	for _, column := range plan.Output {
+		if strings.TrimSpace(column) != "" {
+			output = append(output, column)
+		}
+	}
+
+	return output
+}
+
 func GetTerminator(index int, plan *Plan) string {
 	if index == 0 {
 		if len(plan.Plans) == 0 {
-			return "⌡► "
+			return Glyphs.OutputOnly + " "
 		} else {
-			return "├►  "
+			return Glyphs.OutputFirst + "  "
 		}
 	} else {
 		if len(plan.Plans) == 0 {
 			return "   "
 		} else {
-			return "│  "
+			return Glyphs.Vertical + "  "
 		}
 	}
 }
 
-func WritePlan(writer io.Writer, explain *Explain, plan *Plan, prefix string, depth int, lastChild bool) {
-	currentPrefix := prefix
+func WritePlan(writer io.Writer, explain *Explain, plan *Plan, options Options, prefix string, depth int, lastChild bool) error {
+	var err error
+
+	renderPlan(explain, plan, options, prefix, depth, lastChild, func(line PlanLine) bool {
+		_, err = fmt.Fprintln(writer, line.Text)
+		return err == nil
+	})
+
+	return err
+}
 
-	var Output = func(format string, a ...interface{}) (int, error) {
-		return fmt.Fprintf(writer, fmt.Sprintf("%s%s\n", PrefixFormat(currentPrefix), format), a...)
+// indentWidth is Options.IndentWidth, or DefaultIndentWidth when it is not
+// set. Widths below 2 are raised to 2, the narrowest the connectors fit in.
+func indentWidth(options Options) int {
+	if options.IndentWidth == 0 {
+		return DefaultIndentWidth
 	}
 
-	Output(PrefixFormat("│"))
+	if options.IndentWidth < 2 {
+		return 2
+	}
 
-	joint := "├"
-	if len(plan.Plans) > 1 || lastChild {
-		joint = "└"
+	return options.IndentWidth
+}
+
+// nodeConnector joins joint to the node glyph, drawing the glyph's leading
+// line out so that with a wider indent the node's own rail still starts
+// under the glyph's end.
+func nodeConnector(joint string, width int) string {
+	line, _ := utf8.DecodeRuneInString(Glyphs.Node)
+
+	return joint + strings.Repeat(string(line), width-2) + Glyphs.Node
+}
+
+// renderPlan passes each line of the tree to emit, stopping as soon as emit
+// returns false. It reports whether rendering ran to completion.
+func renderPlan(explain *Explain, plan *Plan, options Options, prefix string, depth int, lastChild bool, emit func(line PlanLine) bool) bool {
+	if options.WarningsOnly && options.warnedBranches == nil {
+		options.warnedBranches = warnedBranches(explain, plan, options)
+	}
+
+	if options.WarningsOnly && depth > 0 && !options.warnedBranches[plan] {
+		return renderClean(explain, plan, options, prefix, depth, lastChild, emit)
 	}
 
-	Output("%v %v%v %v", PrefixFormat(joint+"─⌠"), BoldFormat(plan.NodeType), FormatDetails(plan), FormatTags(plan))
+	currentPrefix := prefix
+	severity := nodeSeverity(explain, plan, options)
+	ok, header := true, false
+
+	var Output = func(format string, a ...interface{}) {
+		if ok {
+			ok = emit(PlanLine{
+				Depth:     depth,
+				NodeIndex: plan.ID,
+				Text:      PrefixFormat(currentPrefix) + fmt.Sprintf(format, a...),
+				Severity:  severity,
+				Header:    header,
+			})
+		}
+	}
+
+	indent := options.Style == StyleIndent
 
+	joint := Glyphs.Branch
 	if len(plan.Plans) > 1 || lastChild {
-		prefix += "  "
+		joint = Glyphs.LastBranch
+	}
+
+	width := indentWidth(options)
+
+	connector := PrefixFormat(nodeConnector(joint, width)) + " "
+
+	if indent {
+		connector = ""
+	} else {
+		Output("%v", PrefixFormat(Glyphs.Vertical))
+	}
+
+	rank := ""
+	if options.ShowCostRank {
+		rank = MutedFormat(fmt.Sprintf(" #%d by cost", plan.CostRank))
+	}
+
+	header = true
+	Output("%v%v %v%v%v%v %v", connector, MutedFormat(fmt.Sprintf("#%d", plan.ID)), FormatJoinSide(plan), EstimateFormat(plan)(plan.NodeType), FormatDetails(plan), rank, FormatTags(plan))
+	header = false
+
+	if len(plan.Plans) > 1 || lastChild || indent {
+		prefix += strings.Repeat(" ", width)
 	} else {
-		prefix += "│ "
+		prefix += Glyphs.Vertical + strings.Repeat(" ", width-1)
+	}
+
+	currentPrefix = prefix + Glyphs.Vertical + " "
+
+	if indent {
+		currentPrefix = prefix
+	}
+
+	// Detail writes lines left out by Options.Triage.
+	var Detail = func(format string, a ...interface{}) {
+		if !options.Triage {
+			Output(format, a...)
+		}
+	}
+
+	var Condition = func(condition string) string {
+		if options.Redact {
+			condition = RedactLiterals(condition)
+		}
+
+		return TruncateCondition(condition, options.MaxConditionLength)
+	}
+
+	if !options.HideDescriptions && !options.Triage {
+		for _, line := range strings.Split(wrapText(Descriptions[plan.NodeType], 60), "\n") {
+			Output("%v", MutedFormat(line))
+		}
 	}
 
-	currentPrefix = prefix + "│ "
+	if !explain.EstimateOnly && !explain.TimingOff && !(options.HideZeroMetrics && plan.ActualDuration == 0) {
+		Output("%v %v %v (%.0f%%)", Glyphs.Bullet, "Duration:", DurationToString(plan.ActualDuration), DurationShare(explain, plan)*100)
+
+		if plan.Runs != nil && plan.Runs.Count > 1 {
+			Output("%v %v %v, %v %v, %v %v, ±%.2f ms", Glyphs.Bullet, "Runs:", plan.Runs.Count, MutedFormat("median"), DurationToString(plan.Runs.Median), MutedFormat("p95"), DurationToString(plan.Runs.P95), plan.Runs.StdDev)
+		}
 
-	for _, line := range strings.Split(wordwrap.WrapString(Descriptions[plan.NodeType], 60), "\n") {
-		Output("%v", MutedFormat(line))
+		if options.ShowCumulative {
+			Output("%v %v %v", Glyphs.Bullet, "Cumulative:", DurationToString(plan.ActualTotalTime))
+		}
 	}
 
-	Output("○ %v %v (%.0f%%)", "Duration:", DurationToString(plan.ActualDuration), (plan.ActualDuration/explain.ExecutionTime)*100)
+	if !(options.HideZeroMetrics && plan.ActualCost == 0) {
+		Output("%v %v %v (%.0f%%)", Glyphs.Bullet, "Cost:", FormatCost(plan.ActualCost), CostShare(explain, plan)*100)
+	}
 
-	Output("○ %v %v (%.0f%%)", "Cost:", humanize.Commaf(plan.ActualCost), (plan.ActualCost/explain.TotalCost)*100)
+	if explain.EstimateOnly {
+		if !(options.HideZeroMetrics && plan.PlanRows == 0) {
+			Output("%v %v %v %v", Glyphs.Bullet, "Rows:", FormatInteger(int64(plan.PlanRows)), MutedFormat("(estimated)"))
+		}
+	} else if !(options.HideZeroMetrics && plan.ActualRows == 0 && !options.ShowEstimates) {
+		rows := FormatInteger(int64(plan.ActualRows))
 
-	Output("○ %v %v", "Rows:", humanize.Comma(int64(plan.ActualRows)))
+		if explain.TimingOff && plan.ActualLoops > 1 {
+			rows += " " + MutedFormat(fmt.Sprintf("× %v loops", FormatInteger(int64(plan.ActualLoops))))
+		}
+
+		if options.ShowEstimates {
+			rows += " " + MutedFormat(fmt.Sprintf("(est %v)", FormatInteger(int64(plan.PlanRows))))
+		}
+
+		Output("%v %v %v", Glyphs.Bullet, "Rows:", rows)
+	}
+
+	if options.ShowTimeline && !explain.EstimateOnly && !explain.TimingOff {
+		Output("%v %v %v", Glyphs.Bullet, "Timeline:", TimelineBar(explain, plan, 20))
+	}
 
 	currentPrefix = currentPrefix + "  "
 
+	if options.ShowPlannerCost {
+		Output("%v cost=%.2f..%.2f rows=%v width=%v", MutedFormat("planner"), plan.StartupCost, plan.TotalCost, plan.PlanRows, plan.PlanWidth)
+	}
+
+	if HasSignificantStartupCost(plan) {
+		Detail("%v %v %v %v", MutedFormat("startup cost"), FormatCost(plan.StartupCost), MutedFormat("run cost"), FormatCost(plan.TotalCost-plan.StartupCost))
+	}
+
+	if plan.LoopCost > 0 {
+		loops := plan.ActualLoops
+		if explain.EstimateOnly {
+			loops = uint64(math.Round(plan.LoopCost / plan.TotalCost))
+		}
+		Detail("%v %v × %v %v = %v", MutedFormat("cost"), FormatCost(plan.TotalCost), FormatInteger(int64(loops)), MutedFormat("loops"), FormatCost(plan.LoopCost))
+	}
+
+	if plan.NodeType == Limit && len(plan.Plans) == 1 && !explain.EstimateOnly {
+		if produced := plan.Plans[0].ActualRows * plan.Plans[0].ActualLoops; produced > plan.ActualRows {
+			Detail("%v %v %v %v", MutedFormat("passed"), FormatInteger(int64(plan.ActualRows)), MutedFormat("of"), FormatInteger(int64(produced))+MutedFormat(" rows its input produced"))
+		}
+	}
+
+	if plan.SubplanName != "" {
+		Output("%v", BoldFormat(plan.SubplanName))
+	}
+
 	if plan.JoinType != "" {
 		Output("%v %v", plan.JoinType, MutedFormat("join"))
 	}
 
 	if plan.RelationName != "" {
-		Output("%v %v.%v", MutedFormat("on"), plan.Schema, plan.RelationName)
+		if options.Triage {
+			Output("%v %v", MutedFormat("on"), plan.RelationName)
+		} else if plan.Alias != "" && plan.Alias != plan.RelationName {
+			Detail("%v %v.%v %v", MutedFormat("on"), plan.Schema, plan.RelationName, MutedFormat("("+plan.Alias+")"))
+		} else {
+			Detail("%v %v.%v", MutedFormat("on"), plan.Schema, plan.RelationName)
+		}
+	}
+
+	if plan.SamplingMethod != "" {
+		sample := fmt.Sprintf("%v(%v)", strings.ToUpper(plan.SamplingMethod), strings.Join(plan.SamplingParameters, ", "))
+		if plan.RepeatableSeed != "" {
+			sample += fmt.Sprintf(" %v %v", MutedFormat("repeatable"), plan.RepeatableSeed)
+		}
+		Detail("%v %v", MutedFormat("sample"), sample)
 	}
 
 	if plan.IndexName != "" {
-		Output("%v %v", MutedFormat("using"), plan.IndexName)
+		Detail("%v %v", MutedFormat("using"), plan.IndexName)
 	}
 
 	if plan.IndexCondition != "" {
-		Output("%v %v", MutedFormat("condition"), plan.IndexCondition)
+		Output("%v %v", MutedFormat("condition"), Condition(plan.IndexCondition))
+	}
+
+	if plan.RecheckCondition != "" {
+		recheck := ""
+		if plan.RowsRemovedByIndexRecheck > 0 {
+			recheck = " " + MutedFormat(fmt.Sprintf("[-%v rows]", FormatInteger(int64(plan.RowsRemovedByIndexRecheck))))
+		}
+		Output("%v %v%v", MutedFormat("recheck"), Condition(plan.RecheckCondition), recheck)
 	}
 
 	if plan.Filter != "" {
-		Output("%v %v %v", MutedFormat("filter"), plan.Filter, MutedFormat(fmt.Sprintf("[-%v rows]", humanize.Comma(int64(plan.RowsRemovedByFilter)))))
+		removed := fmt.Sprintf("[-%v rows]", FormatInteger(int64(plan.RowsRemovedByFilter)))
+		if plan.ActualRows+plan.RowsRemovedByFilter > 0 {
+			removed = fmt.Sprintf("[-%v rows (-%.0f%%)]", FormatInteger(int64(plan.RowsRemovedByFilter)), FilterRemovalRatio(plan)*100)
+		}
+		if options.CumulativeFilter && plan.CumulativeRowsRemoved > plan.RowsRemovedByFilter {
+			removed += fmt.Sprintf(" [-%v rows total]", FormatInteger(int64(plan.CumulativeRowsRemoved)))
+		}
+		Output("%v %v %v", MutedFormat("filter"), Condition(plan.Filter), MutedFormat(removed))
 	}
 
 	if plan.HashCondition != "" {
-		Output("%v %v", MutedFormat("on"), plan.HashCondition)
+		Output("%v %v", MutedFormat("on"), Condition(plan.HashCondition))
+	}
+
+	if plan.MergeCondition != "" {
+		Output("%v %v", MutedFormat("on"), Condition(plan.MergeCondition))
+	}
+
+	if plan.JoinFilter != "" {
+		removed := ""
+		if plan.RowsRemovedByJoinFilter > 0 {
+			removed = " " + MutedFormat(fmt.Sprintf("[-%v rows]", FormatInteger(int64(plan.RowsRemovedByJoinFilter))))
+		}
+		Output("%v %v%v", MutedFormat("join filter"), Condition(plan.JoinFilter), removed)
+	}
+
+	if plan.NodeType == Sort || plan.NodeType == IncrementalSort {
+		Detail("%v %v", MutedFormat("sort"), formatSortDetail(GetSortDetail(plan)))
+	}
+
+	if plan.Storage != "" {
+		storage := fmt.Sprintf("%v %v", FormatBytes(plan.MaximumStorage*1024), strings.ToLower(plan.Storage))
+		if plan.Storage == "Disk" {
+			storage = WarningFormat(storage)
+		}
+		Detail("%v %v", MutedFormat("storage"), storage)
+	}
+
+	if options.WorkMemKB > 0 && UsesWorkMem(plan) {
+		limit, setting := MemoryLimitKB(plan, options.WorkMemKB, options.HashMemMultiplier), "work_mem"
+		if UsesHashMem(plan) {
+			setting = "work_mem × hash_mem_multiplier"
+		}
+
+		if memory := EstimatedMemoryKB(plan); memory > limit {
+			Output("%v ~%v %v %v", WarningFormat("memory"), FormatBytes(memory*1024), MutedFormat("exceeds "+setting+" of"), FormatBytes(limit*1024))
+		}
+	}
+
+	if plan.NodeType == Memoize {
+		if plan.CacheKey != "" {
+			Detail("%v %v", MutedFormat("cache key"), Condition(plan.CacheKey))
+		}
+
+		if lookups := plan.CacheHits + plan.CacheMisses; lookups > 0 {
+			format := GoodFormat
+			if MemoizeHitRate(plan) < MemoizeHitRatio {
+				format = WarningFormat
+			}
+
+			cache := fmt.Sprintf("%v %v %v lookups", format(fmt.Sprintf("%.0f%% hits", MemoizeHitRate(plan)*100)), MutedFormat("of"), FormatInteger(int64(lookups)))
+
+			if plan.CacheEvictions > 0 {
+				cache += fmt.Sprintf(", %v evictions", FormatInteger(int64(plan.CacheEvictions)))
+			}
+
+			if plan.CacheOverflows > 0 {
+				cache += fmt.Sprintf(", %v overflows", FormatInteger(int64(plan.CacheOverflows)))
+			}
+
+			Detail("%v %v", MutedFormat("cache"), cache)
+		}
+	}
+
+	if plan.NodeType == Aggregate && plan.Strategy != "" {
+		Detail("%v %v", MutedFormat("strategy"), BoldFormat(plan.Strategy))
+	}
+
+	if plan.HashAggBatches > 1 || plan.DiskUsage > 0 {
+		Detail("%v %v %v", MutedFormat("batches"), FormatInteger(int64(plan.HashAggBatches)), WarningFormat(FormatBytes(plan.DiskUsage*1024)+" disk"))
+	}
+
+	if len(plan.GroupKey) > 0 {
+		Detail("%v %v", MutedFormat("group by"), strings.Join(plan.GroupKey, ", "))
+	}
+
+	for _, set := range plan.GroupingSets {
+		Detail("%v %v", MutedFormat("grouping sets"), formatGroupingSet(set))
+	}
+
+	if plan.WorkersPlanned > 0 {
+		Detail("%v %v %v %v", MutedFormat("workers"), plan.WorkersLaunched, MutedFormat("launched of"), plan.WorkersPlanned)
+	}
+
+	if plan.TuplestoreName != "" {
+		Detail("%v %v", MutedFormat("on"), plan.TuplestoreName)
+	}
+
+	if plan.ConflictResolution != "" {
+		conflict := fmt.Sprintf("%v DO %v", MutedFormat("on conflict"), strings.ToUpper(plan.ConflictResolution))
+		if len(plan.ConflictArbiterIndexes) > 0 {
+			conflict += fmt.Sprintf(" %v %v", MutedFormat("using"), strings.Join(plan.ConflictArbiterIndexes, ", "))
+		}
+		Detail("%v", conflict)
+
+		if plan.ConflictFilter != "" {
+			Detail("%v %v", MutedFormat("conflict filter"), Condition(plan.ConflictFilter))
+		}
+
+		if !explain.EstimateOnly {
+			Detail("%v %v, %v %v", FormatInteger(int64(plan.TuplesInserted)), MutedFormat("inserted"), FormatInteger(int64(plan.ConflictingTuples)), MutedFormat("conflicting"))
+		}
 	}
 
 	if plan.CTEName != "" {
-		Output("CTE %v", plan.CTEName)
+		Detail("CTE %v", plan.CTEName)
 	}
 
 	if plan.PlannerRowEstimateFactor != 0 {
 		Output("%v %vestimated %v %.2fx", MutedFormat("rows"), plan.PlannerRowEstimateDirection, MutedFormat("by"), plan.PlannerRowEstimateFactor)
 	}
 
+	for _, hint := range planHints(explain, plan, options) {
+		Output("%v %v", WarningFormat("hint"), hint)
+	}
+
+	if annotation, ok := options.Annotations[plan.ID]; ok {
+		for _, line := range strings.Split(annotation, "\n") {
+			Output("%v %v", BoldFormat("#"), line)
+		}
+	}
+
 	currentPrefix = prefix
 
-	if len(plan.Output) > 0 {
-		for index, line := range strings.Split(wordwrap.WrapString(strings.Join(plan.Output, " + "), 60), "\n") {
-			Output(PrefixFormat(GetTerminator(index, plan)) + OutputFormat(line))
+	if output := NonEmptyOutput(plan); len(output) > 0 && !options.OutputAppendix {
+		if options.Redact {
+			for index, column := range output {
+				output[index] = RedactLiterals(column)
+			}
+		}
+
+		for index, line := range strings.Split(wrapText(strings.Join(output, " + "), 60), "\n") {
+			if indent {
+				Output("  %v", OutputFormat(line))
+			} else {
+				Output("%v%v", PrefixFormat(GetTerminator(index, plan)), OutputFormat(line))
+			}
+		}
+	}
+
+	if !ok {
+		return false
+	}
+
+	if options.CollapsePartitions && (plan.NodeType == Append || plan.NodeType == MergeAppend) {
+		groups := GroupSiblings(plan.Plans)
+
+		for index, group := range groups {
+			if len(group) > 1 {
+				ok = renderCollapsed(explain, group, options, prefix, depth+1, index == len(groups)-1, emit)
+			} else {
+				ok = renderPlan(explain, group[0], options, prefix, depth+1, index == len(groups)-1, emit)
+			}
+
+			if !ok {
+				return false
+			}
 		}
+
+		return true
 	}
 
 	for index, _ := range plan.Plans {
-		WritePlan(writer, explain, &plan.Plans[index], prefix, depth+1, index == len(plan.Plans)-1)
+		if !renderPlan(explain, &plan.Plans[index], options, prefix, depth+1, index == len(plan.Plans)-1, emit) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckDepth walks the plan without recursing and returns an error if it is
+// nested deeper than limit, so that very deep plans fail cleanly.
+func CheckDepth(plan *Plan, limit int) error {
+	type entry struct {
+		plan  *Plan
+		depth int
+	}
+
+	stack := []entry{{plan, 0}}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.depth > limit {
+			return fmt.Errorf("plan is nested deeper than %d levels", limit)
+		}
+
+		for index, _ := range current.plan.Plans {
+			stack = append(stack, entry{&current.plan.Plans[index], current.depth + 1})
+		}
+	}
+
+	return nil
+}
+
+// checkDepth is CheckDepth against Options.MaxDepth, falling back to
+// MaxDepth when it is 0, and fails for a negative limit.
+func checkDepth(plan *Plan, maxDepth int) error {
+	if maxDepth == 0 {
+		maxDepth = MaxDepth
+	}
+
+	if maxDepth < 0 {
+		return fmt.Errorf("maximum depth must not be negative, got %d", maxDepth)
+	}
+
+	return CheckDepth(plan, maxDepth)
+}
+
+// Render processes and writes an Explain built in code rather than parsed
+// from JSON.
+func Render(writer io.Writer, explain *Explain) error {
+	if err := checkDepth(&explain.Plan, 0); err != nil {
+		return err
 	}
+
+	ProcessExplain(explain)
+	return WriteExplain(writer, explain, Options{})
 }
 
 func Visualize(writer io.Writer, buffer []byte) error {
-	var explain []Explain
+	return VisualizeWithOptions(writer, buffer, Options{})
+}
 
-	err := json.Unmarshal(buffer, &explain)
+func VisualizeWithOptions(writer io.Writer, buffer []byte, options Options) error {
+	explain, err := analyze(buffer, options.MaxDepth)
 
 	if err != nil {
 		return err
 	}
 
 	for index, _ := range explain {
-		ProcessExplain(&explain[index])
-		WriteExplain(writer, &explain[index])
+		err = WriteExplain(writer, &explain[index], options)
+
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Analyze parses a JSON explain, unwrapped with UnwrapExplain, and runs
+// ProcessExplain on each plan, so the derived fields such as TotalCost and
+// the Max* fields are populated.
+func Analyze(buffer []byte) ([]Explain, error) {
+	return analyze(buffer, 0)
+}
+
+func analyze(buffer []byte, maxDepth int) ([]Explain, error) {
+	var explain []Explain
+
+	err := json.Unmarshal(UnwrapExplain(buffer), &explain)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for index, _ := range explain {
+		err = checkDepth(&explain[index].Plan, maxDepth)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for index, _ := range explain {
+		ProcessExplain(&explain[index])
+	}
+
+	return explain, nil
+}
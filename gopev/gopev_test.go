@@ -0,0 +1,1303 @@
+package gopev
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// analyzeOne parses a single-plan EXPLAIN and fails the test on error.
+func analyzeOne(t *testing.T, buffer string) *Explain {
+	t.Helper()
+
+	explains, err := Analyze([]byte(buffer))
+
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if len(explains) != 1 {
+		t.Fatalf("Analyze returned %d plans, want 1", len(explains))
+	}
+
+	return &explains[0]
+}
+
+// analyzeFile parses a single-plan EXPLAIN from testdata.
+func analyzeFile(t *testing.T, name string) *Explain {
+	t.Helper()
+
+	buffer, err := os.ReadFile("testdata/" + name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return analyzeOne(t, string(buffer))
+}
+
+const nestedLoopFunctionScan = `[{"Plan": {"Node Type": "Nested Loop", "Join Type": "Inner", "Total Cost": 1000.0, "Plan Rows": 10, "Actual Total Time": 500.0, "Actual Rows": 10, "Actual Loops": 1,
+	"Plans": [
+		{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "a", "Schema": "public", "Total Cost": 900.0, "Plan Rows": 100, "Actual Total Time": 20.0, "Actual Rows": 100, "Actual Loops": 1},
+		{"Node Type": "Function Scan", "Parent Relationship": "Inner", "Total Cost": 0.5, "Plan Rows": 1, "Actual Total Time": 4.7, "Actual Rows": 1, "Actual Loops": 100}]},
+	"Planning Time": 0.1, "Execution Time": 500}]`
+
+func TestCalculateCostEstimateScalesCostByLoops(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	inner := &explain.Plan.Plans[1]
+
+	if got := explain.TotalLoopedCost; got != 99.5+900+0.5*100 {
+		t.Errorf("TotalLoopedCost = %v, want %v", got, 99.5+900+0.5*100)
+	}
+
+	average := explain.TotalDuration / explain.TotalLoopedCost
+	want := (inner.ActualDuration / (inner.ActualCost * 100)) / average
+
+	if inner.CostEstimateDirection != Under || inner.CostEstimateFactor != want {
+		t.Errorf("inner estimate = %v %v, want %v %v", inner.CostEstimateDirection, inner.CostEstimateFactor, Under, want)
+	}
+}
+
+func TestCalculateCostEstimateLoopedDirection(t *testing.T) {
+	// The inner Index Scan costs 10 per loop and runs 100 times, 1000 in all,
+	// for 200 ms: 0.2 ms per unit of cost, above the plan's 498 ms / 3000.
+	// Against the unlooped 2010 it would look faster than average.
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Nested Loop", "Join Type": "Inner", "Total Cost": 2010, "Plan Rows": 100, "Actual Total Time": 300, "Actual Rows": 100, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "a", "Schema": "public", "Total Cost": 2000, "Plan Rows": 100, "Actual Total Time": 100, "Actual Rows": 100, "Actual Loops": 1},
+			{"Node Type": "Index Scan", "Parent Relationship": "Inner", "Relation Name": "b", "Schema": "public", "Index Name": "b_pkey", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 2, "Actual Rows": 1, "Actual Loops": 100}]},
+		"Execution Time": 300}]`)
+
+	outer, inner := &explain.Plan.Plans[0], &explain.Plan.Plans[1]
+
+	if inner.CostEstimateDirection != Under {
+		t.Errorf("inner direction = %v, want %v", inner.CostEstimateDirection, Under)
+	}
+
+	if outer.CostEstimateDirection != Over {
+		t.Errorf("outer direction = %v, want %v", outer.CostEstimateDirection, Over)
+	}
+}
+
+func TestCalculateCostEstimateSkipsSmallNodes(t *testing.T) {
+	explain := &Explain{TotalLoopedCost: 100, TotalDuration: 1000}
+	plan := &Plan{ActualCost: 1, ActualLoops: 1, ActualDuration: 40}
+
+	CalculateCostEstimate(explain, plan)
+
+	if plan.CostEstimateFactor != 0 {
+		t.Errorf("factor for a node with 4%% of the time = %v, want 0", plan.CostEstimateFactor)
+	}
+
+	plan.ActualDuration = 500
+	CalculateCostEstimate(explain, plan)
+
+	if plan.CostEstimateDirection != Under || plan.CostEstimateFactor != 50 {
+		t.Errorf("estimate = %v %v, want %v 50", plan.CostEstimateDirection, plan.CostEstimateFactor, Under)
+	}
+
+	for _, warning := range tagWarnings(plan) {
+		if warning.Kind == SlowForCost {
+			return
+		}
+	}
+
+	t.Errorf("node 50x slower than its cost was not tagged %v", SlowForCost)
+}
+
+func TestCalculateCostEstimateOver(t *testing.T) {
+	explain := &Explain{TotalLoopedCost: 100, TotalDuration: 1000}
+	plan := &Plan{ActualCost: 50, ActualLoops: 1, ActualDuration: 100}
+
+	CalculateCostEstimate(explain, plan)
+
+	if plan.CostEstimateDirection != Over || plan.CostEstimateFactor != 5 {
+		t.Errorf("estimate = %v %v, want %v 5", plan.CostEstimateDirection, plan.CostEstimateFactor, Over)
+	}
+}
+
+func TestWriteExplainRestoresGlobals(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	prefix, glyphs, integer := PrefixFormat("x"), Glyphs, FormatInteger(1234567)
+
+	var rendered strings.Builder
+	err := WriteExplain(&rendered, explain, Options{
+		Theme:   &NoColorTheme,
+		Symbols: &ASCIISymbols,
+		Numbers: &NumberFormat{Thousands: ".", Decimal: ","},
+	})
+
+	if err != nil {
+		t.Fatalf("WriteExplain: %v", err)
+	}
+
+	if !strings.Contains(rendered.String(), "1.000") {
+		t.Errorf("render did not use the number format:\n%v", rendered.String())
+	}
+
+	if PrefixFormat("x") != prefix || Glyphs != glyphs || FormatInteger(1234567) != integer {
+		t.Errorf("globals not restored: %q %+v %q", PrefixFormat("x"), Glyphs, FormatInteger(1234567))
+	}
+}
+
+func renderPlain(t *testing.T, explain *Explain, options Options) string {
+	t.Helper()
+
+	options.Theme = &NoColorTheme
+
+	var rendered strings.Builder
+
+	if err := WriteExplain(&rendered, explain, options); err != nil {
+		t.Fatalf("WriteExplain: %v", err)
+	}
+
+	return rendered.String()
+}
+
+func TestIndentWidthAlignsRails(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	for _, width := range []int{2, 3, 6} {
+		lines := strings.Split(renderPlain(t, explain, Options{IndentWidth: width}), "\n")
+
+		for index, line := range lines[:len(lines)-1] {
+			column := strings.Index(line, "⌠")
+			if column < 0 {
+				continue
+			}
+
+			glyph := len([]rune(line[:column]))
+			next := []rune(lines[index+1])
+
+			if len(next) <= glyph || next[glyph] != '│' {
+				t.Errorf("width %d: rail below %q is not under the node glyph: %q", width, line, lines[index+1])
+			}
+		}
+	}
+}
+
+func TestIndentWidthCollapsedPartitions(t *testing.T) {
+	explain := analyzeFile(t, "partitioned.json")
+
+	for _, width := range []int{2, 5} {
+		rendered := renderPlain(t, explain, Options{IndentWidth: width, CollapsePartitions: true})
+		connector := strings.Repeat("─", width-1) + "⌠"
+
+		if !strings.Contains(rendered, "×4 Seq Scan partition scans") {
+			t.Fatalf("width %d: partition scans not collapsed:\n%v", width, rendered)
+		}
+
+		for _, line := range strings.Split(rendered, "\n") {
+			if strings.Contains(line, "⌠") && !strings.Contains(line, connector) {
+				t.Errorf("width %d: connector of %q is not %q", width, line, connector)
+			}
+		}
+	}
+}
+
+func TestIndentWidthClamps(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	want := renderPlain(t, explain, Options{IndentWidth: 2})
+
+	for _, width := range []int{0, 1, -3} {
+		if got := renderPlain(t, explain, Options{IndentWidth: width}); got != want {
+			t.Errorf("width %d rendered differently from width 2:\n%v", width, got)
+		}
+	}
+}
+
+// nested builds a plan of Materialize nodes nested depth levels deep.
+func nested(depth int) Explain {
+	explain := Explain{Plan: Plan{NodeType: Materialize}}
+	plan := &explain.Plan
+
+	for level := 0; level < depth; level++ {
+		plan.Plans = []Plan{{NodeType: Materialize}}
+		plan = &plan.Plans[0]
+	}
+
+	return explain
+}
+
+func TestCheckDepth(t *testing.T) {
+	explain := nested(5)
+
+	if err := CheckDepth(&explain.Plan, 5); err != nil {
+		t.Errorf("CheckDepth(5 levels, 5) = %v, want nil", err)
+	}
+
+	if err := CheckDepth(&explain.Plan, 4); err == nil {
+		t.Errorf("CheckDepth(5 levels, 4) = nil, want an error")
+	}
+}
+
+func TestMaxDepthLimits(t *testing.T) {
+	deep := nested(DefaultMaxDepth + 1)
+
+	if err := Render(io.Discard, &deep); err == nil {
+		t.Errorf("Render accepted a plan deeper than DefaultMaxDepth")
+	}
+
+	buffer := []byte(`[{"Plan": {"Node Type": "Limit", "Plans": [{"Node Type": "Seq Scan", "Relation Name": "t"}]}}]`)
+
+	tests := []struct {
+		maxDepth int
+		ok       bool
+	}{
+		{0, true},
+		{1, true},
+		{2, true},
+		{-1, false},
+	}
+
+	for _, test := range tests {
+		err := VisualizeWithOptions(io.Discard, buffer, Options{MaxDepth: test.maxDepth})
+
+		if (err == nil) != test.ok {
+			t.Errorf("VisualizeWithOptions(MaxDepth %d) = %v, want ok %v", test.maxDepth, err, test.ok)
+		}
+
+		_, err = LintWithOptions(io.Discard, buffer, Options{MaxDepth: test.maxDepth})
+
+		if (err == nil) != test.ok {
+			t.Errorf("LintWithOptions(MaxDepth %d) = %v, want ok %v", test.maxDepth, err, test.ok)
+		}
+	}
+
+	previous := MaxDepth
+	defer func() { MaxDepth = previous }()
+
+	MaxDepth = 0
+
+	if _, err := Analyze(buffer); err == nil {
+		t.Errorf("Analyze accepted a 2 level plan with MaxDepth 0")
+	}
+
+	if err := VisualizeFlame(io.Discard, buffer, 40); err == nil {
+		t.Errorf("VisualizeFlame accepted a 2 level plan with MaxDepth 0")
+	}
+
+	MaxDepth = -1
+
+	if _, err := Analyze(buffer); err == nil {
+		t.Errorf("Analyze accepted a negative MaxDepth")
+	}
+}
+
+func TestShowCumulative(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "Cumulative:") {
+		t.Errorf("cumulative time shown without ShowCumulative:\n%v", rendered)
+	}
+
+	rendered := renderPlain(t, explain, Options{ShowCumulative: true})
+
+	for _, want := range []string{"○ Cumulative: 500.00 ms", "○ Cumulative: 20.00 ms", "○ Cumulative: 4.70 ms"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered plan is missing %q:\n%v", want, rendered)
+		}
+	}
+}
+
+func TestAggregateStrategyAndGroupKey(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Aggregate", "Strategy": "Hashed", "Group Key": ["t.a", "t.b"], "Total Cost": 20, "Plan Rows": 10, "Actual Total Time": 2, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 100, "Actual Total Time": 1, "Actual Rows": 100, "Actual Loops": 1}]},
+		"Execution Time": 2}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	for _, want := range []string{"strategy Hashed", "group by t.a, t.b"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered plan is missing %q:\n%v", want, rendered)
+		}
+	}
+
+	if strings.Count(rendered, "strategy") != 1 {
+		t.Errorf("strategy shown on a node other than the Aggregate:\n%v", rendered)
+	}
+}
+
+func TestRenderBuiltExplain(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	color.NoColor = true
+
+	explain := &Explain{ExecutionTime: 3, Plan: Plan{
+		NodeType: Limit, TotalCost: 12, PlanRows: 10, ActualTotalTime: 3, ActualRows: 10, ActualLoops: 1,
+		Plans: []Plan{
+			{NodeType: SequenceScan, RelationName: "t", Schema: "public", TotalCost: 10, PlanRows: 1000, ActualTotalTime: 2, ActualRows: 10, ActualLoops: 1},
+		},
+	}}
+
+	var rendered strings.Builder
+
+	if err := Render(&rendered, explain); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	if explain.TotalCost != 12 || explain.Plan.Plans[0].ID != 2 {
+		t.Errorf("Render did not process the plan: TotalCost %v, child ID %v", explain.TotalCost, explain.Plan.Plans[0].ID)
+	}
+
+	for _, want := range []string{"#1 Limit", "#2 Seq Scan", "on public.t"} {
+		if !strings.Contains(rendered.String(), want) {
+			t.Errorf("rendered plan is missing %q:\n%v", want, rendered.String())
+		}
+	}
+}
+
+func TestFilterRemovalRatio(t *testing.T) {
+	tests := []struct {
+		rows, removed uint64
+		want          float64
+	}{
+		{0, 0, 0},
+		{100, 0, 0},
+		{0, 50, 1},
+		{25, 75, 0.75},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{ActualRows: test.rows, RowsRemovedByFilter: test.removed}
+
+		if got := FilterRemovalRatio(plan); got != test.want {
+			t.Errorf("FilterRemovalRatio(%v rows, %v removed) = %v, want %v", test.rows, test.removed, got, test.want)
+		}
+	}
+}
+
+func TestFilterLineShowsRemovedShare(t *testing.T) {
+	explain := analyzeFile(t, "nestedloop.json")
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "filter (o.total > '500'::numeric) [-48,796 rows (-98%)]") {
+		t.Errorf("filter line is missing the removed share:\n%v", rendered)
+	}
+}
+
+func TestHideDescriptions(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	description := "Merges two record sets by looping through every record in"
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, description) {
+		t.Errorf("description missing by default:\n%v", rendered)
+	}
+
+	rendered := renderPlain(t, explain, Options{HideDescriptions: true})
+
+	for _, description := range Descriptions {
+		first := strings.Fields(description)[0]
+
+		if strings.Contains(rendered, description[:40]) {
+			t.Errorf("description starting %q shown with HideDescriptions:\n%v", first, rendered)
+		}
+	}
+
+	if !strings.Contains(rendered, "#1 Nested Loop") {
+		t.Errorf("HideDescriptions dropped the node itself:\n%v", rendered)
+	}
+}
+
+func TestCostShares(t *testing.T) {
+	explain := analyzeFile(t, "nestedloop.json")
+
+	if total := CostSharePercentage(explain, &explain.Plan); math.Abs(total-100) > 1e-9 {
+		t.Errorf("CostSharePercentage = %v, want 100", total)
+	}
+
+	root := &explain.Plan
+
+	if got, want := CostShare(explain, root), root.ActualCost/explain.TotalCost; got != want {
+		t.Errorf("CostShare = %v, want %v", got, want)
+	}
+
+	if got, want := DurationShare(explain, root), root.ActualDuration/explain.ExecutionTime; got != want {
+		t.Errorf("DurationShare = %v, want %v", got, want)
+	}
+
+	empty := &Explain{}
+
+	if CostShare(empty, root) != 0 || DurationShare(empty, root) != 0 {
+		t.Errorf("shares of a plan without totals = %v, %v, want 0, 0", CostShare(empty, root), DurationShare(empty, root))
+	}
+}
+
+func TestZeroTotalCostRendersWithoutNaN(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Result", "Total Cost": 0, "Plan Rows": 1, "Actual Total Time": 0.01, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 0}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	if strings.Contains(rendered, "NaN") || strings.Contains(rendered, "Inf") {
+		t.Errorf("plan with no cost or time rendered non-numbers:\n%v", rendered)
+	}
+
+	if !strings.Contains(rendered, "○ Cost: 0 (0%)") {
+		t.Errorf("zero cost not shown as 0%%:\n%v", rendered)
+	}
+}
+
+func TestTruncateCondition(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	tests := []struct {
+		condition string
+		limit     int
+		want      string
+	}{
+		{"(a = 1)", 0, "(a = 1)"},
+		{"(a = 1)", 7, "(a = 1)"},
+		{"(a = 1)", -5, "(a = 1)"},
+		{"(a = 1) AND (b = 2)", 7, "(a = 1)… (19 chars)"},
+		{"(名前 = '東京')", 4, "(名前 … (11 chars)"},
+	}
+
+	for _, test := range tests {
+		if got := TruncateCondition(test.condition, test.limit); got != test.want {
+			t.Errorf("TruncateCondition(%q, %d) = %q, want %q", test.condition, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestMaxConditionLength(t *testing.T) {
+	explain := analyzeFile(t, "nestedloop.json")
+	rendered := renderPlain(t, explain, Options{MaxConditionLength: 10})
+
+	if !strings.Contains(rendered, "filter (o.total >… (26 chars)") {
+		t.Errorf("filter not truncated to 10 characters:\n%v", rendered)
+	}
+}
+
+func TestMaxConditionLengthInList(t *testing.T) {
+	var ids []string
+
+	for id := 100000; len(strings.Join(ids, ",")) < 1960; id++ {
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	condition := fmt.Sprintf("(id = ANY ('{%v}'::integer[]))", strings.Join(ids, ","))
+	buffer, _ := json.Marshal(condition)
+
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": `+string(buffer)+`, "Total Cost": 10, "Plan Rows": 5, "Actual Total Time": 1, "Actual Rows": 5, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	if !strings.Contains(renderPlain(t, explain, Options{}), "filter "+condition+" ") {
+		t.Errorf("%d-character filter not shown in full without MaxConditionLength", len(condition))
+	}
+
+	rendered := renderPlain(t, explain, Options{MaxConditionLength: 60})
+	want := fmt.Sprintf("filter %v… (%v chars) [-0 rows (-0%%)]\n", condition[:60], FormatInteger(int64(len(condition))))
+
+	if !strings.Contains(rendered, want) {
+		t.Errorf("%d-character filter not truncated to one line of 60 characters, want %q:\n%v", len(condition), want, rendered)
+	}
+
+	if strings.Contains(rendered, ids[len(ids)-1]) {
+		t.Errorf("truncated output still holds the end of the IN list:\n%v", rendered)
+	}
+}
+
+func TestCalculatePlanKind(t *testing.T) {
+	tests := []struct {
+		buffer       string
+		estimateOnly bool
+		generic      bool
+	}{
+		{`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(id = 42)", "Total Cost": 10, "Plan Rows": 5}}]`, true, false},
+		{`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(id = $1)", "Total Cost": 10, "Plan Rows": 5}}]`, true, true},
+		{`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(id = $1)", "Total Cost": 10, "Plan Rows": 5, "Actual Total Time": 1, "Actual Rows": 5, "Actual Loops": 1}, "Execution Time": 1}]`, false, false},
+	}
+
+	for _, test := range tests {
+		explain := analyzeOne(t, test.buffer)
+
+		if explain.EstimateOnly != test.estimateOnly || explain.Generic != test.generic {
+			t.Errorf("%v: EstimateOnly, Generic = %v, %v, want %v, %v", explain.Plan.Filter, explain.EstimateOnly, explain.Generic, test.estimateOnly, test.generic)
+		}
+	}
+}
+
+func TestEstimateOnlyOutput(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(id = $1)", "Total Cost": 10, "Plan Rows": 1500}}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	if !strings.Contains(rendered, "Generic Plan (estimates only)") {
+		t.Errorf("output missing the generic plan label:\n%v", rendered)
+	}
+
+	if !strings.Contains(rendered, "Rows: 1,500 (estimated)") {
+		t.Errorf("output missing the estimated row count:\n%v", rendered)
+	}
+
+	for _, absent := range []string{"Execution Time", "Duration:", "NaN"} {
+		if strings.Contains(rendered, absent) {
+			t.Errorf("estimate-only output contains %q:\n%v", absent, rendered)
+		}
+	}
+}
+
+func labeled(name string) Format {
+	return func(a ...interface{}) string {
+		return name + "(" + fmt.Sprint(a...) + ")"
+	}
+}
+
+func TestEstimateFormat(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	labels := Theme{
+		Prefix:   labeled("prefix"),
+		Tag:      labeled("tag"),
+		Muted:    labeled("muted"),
+		Bold:     labeled("bold"),
+		Good:     labeled("good"),
+		Warning:  labeled("warning"),
+		Critical: labeled("critical"),
+		Output:   labeled("output"),
+	}
+	labels.Apply()
+
+	tests := []struct {
+		direction EstimateDirection
+		factor    float64
+		want      string
+	}{
+		{Under, 0, "bold(x)"},
+		{Under, 9.9, "good(x)"},
+		{Under, 10, "warning(x)"},
+		{Under, 100, "critical(x)"},
+		{Over, 99, "warning(x)"},
+		{Over, 100, "critical(x)"},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{PlannerRowEstimateDirection: test.direction, PlannerRowEstimateFactor: test.factor}
+
+		if got := EstimateFormat(plan)("x"); got != test.want {
+			t.Errorf("EstimateFormat(%v %v) = %q, want %q", test.direction, test.factor, got, test.want)
+		}
+	}
+}
+
+func TestNonEmptyOutput(t *testing.T) {
+	plan := &Plan{Output: []string{"t.a", "", "  ", "t.b"}}
+
+	got := NonEmptyOutput(plan)
+
+	if len(got) != 2 || got[0] != "t.a" || got[1] != "t.b" {
+		t.Errorf("NonEmptyOutput = %q, want [t.a t.b]", got)
+	}
+
+	if got := NonEmptyOutput(&Plan{Output: []string{""}}); got != nil {
+		t.Errorf("NonEmptyOutput of blank columns = %q, want nil", got)
+	}
+}
+
+func TestNamedTuplestoreScan(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Named Tuplestore Scan", "Tuplestore Name": "new_rows", "Total Cost": 10, "Plan Rows": 100, "Actual Total Time": 1, "Actual Rows": 100, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	for _, want := range []string{"Named Tuplestore Scan", "on new_rows", "Scans a named tuplestore"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+}
+
+func TestAnalyzeDerivedTotals(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	var cost, duration, maxCost, maxDuration float64
+	var maxRows uint64
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		cost += plan.ActualCost
+		duration += plan.ActualDuration
+		maxCost = math.Max(maxCost, plan.ActualCost)
+		maxDuration = math.Max(maxDuration, plan.ActualDuration)
+
+		if plan.ActualRows > maxRows {
+			maxRows = plan.ActualRows
+		}
+	})
+
+	if math.Abs(explain.TotalCost-cost) > 1e-9 || math.Abs(explain.TotalDuration-duration) > 1e-9 {
+		t.Errorf("TotalCost, TotalDuration = %v, %v, want %v, %v", explain.TotalCost, explain.TotalDuration, cost, duration)
+	}
+
+	if explain.MaxCost != maxCost || explain.MaxDuration != maxDuration || explain.MaxRows != maxRows {
+		t.Errorf("MaxCost, MaxDuration, MaxRows = %v, %v, %v, want %v, %v, %v", explain.MaxCost, explain.MaxDuration, explain.MaxRows, maxCost, maxDuration, maxRows)
+	}
+
+	if explain.MaxRows != 100 {
+		t.Errorf("MaxRows = %v, want 100", explain.MaxRows)
+	}
+}
+
+func TestAnalyzeRejectsInvalidJSON(t *testing.T) {
+	if _, err := Analyze([]byte(`{"Plan": `)); err == nil {
+		t.Errorf("Analyze of truncated JSON returned no error")
+	}
+}
+
+func TestShowPlannerCost(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Startup Cost": 0, "Total Cost": 35.5, "Plan Rows": 2550, "Plan Width": 4, "Actual Total Time": 1, "Actual Rows": 2550, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "planner cost=") {
+		t.Errorf("planner cost shown without ShowPlannerCost:\n%v", rendered)
+	}
+
+	if rendered := renderPlain(t, explain, Options{ShowPlannerCost: true}); !strings.Contains(rendered, "planner cost=0.00..35.50 rows=2550 width=4") {
+		t.Errorf("output missing the planner cost range:\n%v", rendered)
+	}
+}
+
+func TestWriteFooter(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	tests := []struct {
+		buffer string
+		want   string
+	}{
+		{
+			`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 35.5, "Plan Rows": 2550, "Actual Total Time": 1.5, "Actual Rows": 2550, "Actual Loops": 1}, "Execution Time": 1.5}]`,
+			"○ 1.50 ms · cost 35.5 · 1 nodes · peak 2,550 rows · no warnings · score 100\n",
+		},
+		{
+			`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 35.5, "Plan Rows": 2550}}]`,
+			"○ cost 35.5 · 1 nodes · peak 2,550 rows · no warnings · score 100\n",
+		},
+	}
+
+	for _, test := range tests {
+		var written strings.Builder
+		WriteFooter(&written, analyzeOne(t, test.buffer))
+
+		if written.String() != test.want {
+			t.Errorf("WriteFooter = %q, want %q", written.String(), test.want)
+		}
+	}
+}
+
+func TestAssignIDsPreOrder(t *testing.T) {
+	explain := Explain{Plan: Plan{NodeType: NestedLoop, Plans: []Plan{
+		{NodeType: Hash, Plans: []Plan{{NodeType: SequenceScan}}},
+		{NodeType: IndexScan},
+	}}}
+
+	AssignIDs(&explain)
+
+	ids := []int{explain.Plan.ID, explain.Plan.Plans[0].ID, explain.Plan.Plans[0].Plans[0].ID, explain.Plan.Plans[1].ID}
+
+	for index, id := range ids {
+		if id != index+1 {
+			t.Errorf("AssignIDs = %v, want [1 2 3 4]", ids)
+			break
+		}
+	}
+
+	if rendered := renderPlain(t, analyzeOne(t, nestedLoopFunctionScan), Options{}); !strings.Contains(rendered, "#3 inner Function Scan") {
+		t.Errorf("output missing the node id:\n%v", rendered)
+	}
+}
+
+func TestEffectiveLoops(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want float64
+	}{
+		{Plan{ActualLoops: 100}, 100},
+		{Plan{ActualLoops: 3, Parallel: true, GatherWorkersLaunched: 2}, 1},
+		{Plan{ActualLoops: 30, Parallel: true, GatherWorkersLaunched: 2}, 10},
+		{Plan{ActualLoops: 1, Parallel: true, GatherWorkersLaunched: 4}, 1},
+		{Plan{ActualLoops: 0, Parallel: true, GatherWorkersLaunched: 2}, 0},
+	}
+
+	for _, test := range tests {
+		if got := EffectiveLoops(&test.plan); got != test.want {
+			t.Errorf("EffectiveLoops(%v loops, parallel %v, %v workers) = %v, want %v", test.plan.ActualLoops, test.plan.Parallel, test.plan.GatherWorkersLaunched, got, test.want)
+		}
+	}
+}
+
+func TestProcessExplainTwice(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	first := *explain
+	duration, cost := explain.Plan.Plans[1].ActualDuration, explain.Plan.ActualCost
+
+	ProcessExplain(explain)
+
+	if explain.Plan.Plans[1].ActualDuration != duration || explain.Plan.ActualCost != cost {
+		t.Errorf("second ProcessExplain changed the node actuals")
+	}
+
+	if explain.TotalCost != first.TotalCost || explain.TotalDuration != first.TotalDuration || explain.MaxRows != first.MaxRows || explain.MaxCost != first.MaxCost || explain.MaxDuration != first.MaxDuration {
+		t.Errorf("second ProcessExplain changed the totals from %+v to %+v", first, *explain)
+	}
+}
+
+func TestDurationToString(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	labels := Theme{Good: labeled("good"), Warning: labeled("warning"), Critical: labeled("critical")}
+	labels.Apply()
+
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0.5, "good(<1 ms)"},
+		{0.99, "good(<1 ms)"},
+		{1, "good(1.00 ms)"},
+		{99.5, "good(99.50 ms)"},
+		{100, "warning(100.00 ms)"},
+		{999, "warning(999.00 ms)"},
+		{999.99, "warning(999.99 ms)"},
+		{1000, "critical(1.00 s)"},
+		{2500, "critical(2.50 s)"},
+		{59990, "critical(59.99 s)"},
+		{60000, "critical(1.00 m)"},
+		{90000, "critical(1.50 m)"},
+	}
+
+	for _, test := range tests {
+		if got := DurationToString(test.value); got != test.want {
+			t.Errorf("DurationToString(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestDurationSeverity(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  Severity
+	}{
+		{0, SeverityGood},
+		{99.99, SeverityGood},
+		{100, SeverityWarning},
+		{999.99, SeverityWarning},
+		{1000, SeverityCritical},
+		{60000, SeverityCritical},
+	}
+
+	for _, test := range tests {
+		if got := DurationSeverity(test.value); got != test.want {
+			t.Errorf("DurationSeverity(%v) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestOutliers(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Hash Join", "Total Cost": 0, "Plan Rows": 10,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "a", "Schema": "public", "Total Cost": 0, "Plan Rows": 5000},
+			{"Node Type": "Hash", "Parent Relationship": "Inner", "Total Cost": 0, "Plan Rows": 20}]}}]`)
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		if plan.Costliest || plan.Slowest {
+			t.Errorf("#%d %v tagged as an outlier of all-zero costs and durations", plan.ID, plan.NodeType)
+		}
+
+		if plan.Largest != (plan.ID == 2) {
+			t.Errorf("#%d %v Largest = %v, want the Seq Scan by planner rows", plan.ID, plan.NodeType, plan.Largest)
+		}
+	})
+
+	if explain.MaxRows != 5000 {
+		t.Errorf("MaxRows = %v, want 5000", explain.MaxRows)
+	}
+}
+
+func TestTimelineBar(t *testing.T) {
+	explain := &Explain{ExecutionTime: 100}
+
+	tests := []struct {
+		plan Plan
+		want string
+	}{
+		{Plan{ActualStartupTime: 0, ActualTotalTime: 100}, "[██████████]"},
+		{Plan{ActualStartupTime: 50, ActualTotalTime: 80}, "[     ███  ]"},
+		{Plan{ActualStartupTime: 100, ActualTotalTime: 100}, "[         █]"},
+		{Plan{ActualStartupTime: 0, ActualTotalTime: 200}, "[██████████]"},
+	}
+
+	for _, test := range tests {
+		if got := TimelineBar(explain, &test.plan, 10); got != test.want {
+			t.Errorf("TimelineBar(%v..%v) = %q, want %q", test.plan.ActualStartupTime, test.plan.ActualTotalTime, got, test.want)
+		}
+	}
+
+	if got := TimelineBar(&Explain{}, &Plan{}, 4); got != "[    ]" {
+		t.Errorf("TimelineBar without execution time = %q, want an empty bar", got)
+	}
+}
+
+func TestShowTimeline(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "Timeline:") {
+		t.Errorf("timeline shown without ShowTimeline:\n%v", rendered)
+	}
+
+	if rendered := renderPlain(t, explain, Options{ShowTimeline: true}); strings.Count(rendered, "Timeline:") != 3 {
+		t.Errorf("output with ShowTimeline lacks a timeline per node:\n%v", rendered)
+	}
+}
+
+func TestCalculateJoinSides(t *testing.T) {
+	plan := &Plan{NodeType: HashJoin, JoinType: "Inner", Plans: []Plan{
+		{NodeType: SequenceScan, ParentRelationship: "Outer"},
+		{NodeType: Hash, ParentRelationship: "Inner"},
+	}}
+
+	CalculateJoinSides(plan)
+
+	if plan.Plans[0].JoinSide != "outer" || plan.Plans[1].JoinSide != "inner" {
+		t.Errorf("join sides = %q, %q, want outer, inner", plan.Plans[0].JoinSide, plan.Plans[1].JoinSide)
+	}
+
+	members := &Plan{NodeType: Append, Plans: []Plan{{NodeType: SequenceScan, ParentRelationship: "Outer"}}}
+
+	CalculateJoinSides(members)
+
+	if members.Plans[0].JoinSide != "" {
+		t.Errorf("child of a non-join labelled %q", members.Plans[0].JoinSide)
+	}
+}
+
+func TestSampleScan(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Sample Scan", "Relation Name": "t", "Schema": "public", "Sampling Method": "system", "Sampling Parameters": ["'10'::real"], "Repeatable Seed": "'42'::double precision", "Total Cost": 10, "Plan Rows": 100, "Actual Total Time": 1, "Actual Rows": 95, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	for _, want := range []string{"#1 Sample Scan", "sample SYSTEM('10'::real) repeatable '42'::double precision", "Reads a random sample of a table's pages or rows"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+}
+
+func TestCumulativeFilter(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Subquery Scan", "Filter": "(s.n > 1)", "Rows Removed by Filter": 300, "Total Cost": 20, "Plan Rows": 10, "Actual Total Time": 2, "Actual Rows": 100, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Parent Relationship": "Subquery", "Relation Name": "t", "Schema": "public", "Filter": "(t.a = 1)", "Rows Removed by Filter": 600, "Total Cost": 10, "Plan Rows": 400, "Actual Total Time": 1, "Actual Rows": 400, "Actual Loops": 1}]},
+		"Execution Time": 2}]`)
+
+	if got := explain.Plan.Plans[0].CumulativeRowsRemoved; got != 900 {
+		t.Errorf("CumulativeRowsRemoved = %v, want 900", got)
+	}
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "rows total") {
+		t.Errorf("cumulative rows shown without CumulativeFilter:\n%v", rendered)
+	}
+
+	rendered := renderPlain(t, explain, Options{CumulativeFilter: true})
+
+	if strings.Count(rendered, "rows total]") != 1 || !strings.Contains(rendered, "[-600 rows (-60%)] [-900 rows total]") {
+		t.Errorf("output with CumulativeFilter lacks the running total:\n%v", rendered)
+	}
+}
+
+func TestNormalizeNodeType(t *testing.T) {
+	tests := []struct {
+		nodeType NodeType
+		want     NodeType
+		parallel bool
+	}{
+		{"Parallel Seq Scan", SequenceScan, true},
+		{"Parallel Bitmap Heap Scan", BitmapHeapScan, true},
+		{SequenceScan, SequenceScan, false},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{NodeType: test.nodeType}
+		NormalizeNodeType(plan)
+
+		if plan.NodeType != test.want || plan.ParallelAware != test.parallel {
+			t.Errorf("NormalizeNodeType(%q) = %q, parallel %v, want %q, %v", test.nodeType, plan.NodeType, plan.ParallelAware, test.want, test.parallel)
+		}
+	}
+
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Parallel Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "#1 Seq Scan [Parallel]") {
+		t.Errorf("output does not show the normalized parallel node:\n%v", rendered)
+	}
+}
+
+func TestHideZeroMetrics(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Limit", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Result", "Parent Relationship": "Outer", "Total Cost": 10, "Plan Rows": 0, "Actual Total Time": 1, "Actual Rows": 0, "Actual Loops": 1}]},
+		"Execution Time": 1}]`)
+
+	full := renderPlain(t, explain, Options{})
+	hidden := renderPlain(t, explain, Options{HideZeroMetrics: true})
+
+	for _, line := range []string{"Duration: <1 ms (0%)", "Cost: 0 (0%)", "Rows: 0"} {
+		if !strings.Contains(full, line) {
+			t.Errorf("output missing %q:\n%v", line, full)
+		}
+
+		if strings.Contains(hidden, line) {
+			t.Errorf("output with HideZeroMetrics contains %q:\n%v", line, hidden)
+		}
+	}
+
+	if !strings.Contains(hidden, "Rows: 1") {
+		t.Errorf("HideZeroMetrics dropped a nonzero row count:\n%v", hidden)
+	}
+}
+
+func TestModifyTableOnConflict(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "ModifyTable", "Operation": "Insert", "Relation Name": "t", "Schema": "public", "Conflict Resolution": "UPDATE", "Conflict Arbiter Indexes": ["t_pkey"], "Conflict Filter": "(t.version < excluded.version)", "Tuples Inserted": 90, "Conflicting Tuples": 10, "Total Cost": 10, "Plan Rows": 100, "Actual Total Time": 1, "Actual Rows": 0, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	for _, want := range []string{"#1 ModifyTable [Insert]", "on conflict DO UPDATE using t_pkey", "conflict filter (t.version < excluded.version)", "90 inserted, 10 conflicting"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+}
+
+func TestRelationAlias(t *testing.T) {
+	scan := func(alias string) string {
+		return `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "` + alias + `", "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}]`
+	}
+
+	tests := []struct {
+		alias string
+		want  string
+	}{
+		{"o", "on public.orders (o)\n"},
+		{"orders", "on public.orders\n"},
+		{"", "on public.orders\n"},
+	}
+
+	for _, test := range tests {
+		if rendered := renderPlain(t, analyzeOne(t, scan(test.alias)), Options{}); !strings.Contains(rendered, test.want) {
+			t.Errorf("alias %q: output missing %q:\n%v", test.alias, test.want, rendered)
+		}
+	}
+}
+
+func TestTimingOff(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Nested Loop", "Total Cost": 100, "Plan Rows": 10, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "a", "Schema": "public", "Total Cost": 50, "Plan Rows": 10, "Actual Rows": 10, "Actual Loops": 1},
+			{"Node Type": "Index Scan", "Parent Relationship": "Inner", "Relation Name": "b", "Schema": "public", "Index Name": "b_pkey", "Total Cost": 0.5, "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 10}]},
+		"Execution Time": 3.5}]`)
+
+	if !explain.TimingOff || explain.EstimateOnly {
+		t.Fatalf("TimingOff, EstimateOnly = %v, %v, want true, false", explain.TimingOff, explain.EstimateOnly)
+	}
+
+	rendered := renderPlain(t, explain, Options{ShowTimeline: true})
+
+	for _, want := range []string{"Timing Off (rows and loops only)", "Rows: 1 × 10 loops"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+
+	for _, absent := range []string{"Duration:", "Timeline:"} {
+		if strings.Contains(rendered, absent) {
+			t.Errorf("output with timing off contains %q:\n%v", absent, rendered)
+		}
+	}
+
+	if timed := analyzeOne(t, nestedLoopFunctionScan); timed.TimingOff {
+		t.Errorf("TimingOff set on a plan with timings")
+	}
+}
+
+func TestLimitPassedRows(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Limit", "Total Cost": 10, "Plan Rows": 10, "Actual Total Time": 1, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "t", "Schema": "public", "Total Cost": 100, "Plan Rows": 10000, "Actual Total Time": 1, "Actual Rows": 2500, "Actual Loops": 1}]},
+		"Execution Time": 1}]`)
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "passed 10 of 2,500 rows its input produced") {
+		t.Errorf("output missing the rows passed by the Limit:\n%v", rendered)
+	}
+
+	explain.Plan.Plans[0].ActualRows = 10
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "passed") {
+		t.Errorf("Limit that passed all its input noted:\n%v", rendered)
+	}
+}
+
+func TestIndentStyle(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	rendered := renderPlain(t, explain, Options{Style: StyleIndent, HideDescriptions: true})
+
+	for _, glyph := range []string{"│", "├", "└", "┬", "⌠"} {
+		if strings.Contains(rendered, glyph) {
+			t.Errorf("indent style draws %q:\n%v", glyph, rendered)
+		}
+	}
+
+	indents := map[string]int{"#1 Nested Loop": 0, "#2 outer Seq Scan": 2, "#3 inner Function Scan": 2}
+
+	for header, want := range indents {
+		found := false
+
+		for _, line := range strings.Split(rendered, "\n") {
+			if trimmed := strings.TrimLeft(line, " "); strings.HasPrefix(trimmed, header) {
+				found = true
+
+				if column := len(line) - len(trimmed); column != want {
+					t.Errorf("%q indented by %d, want %d", header, column, want)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("output missing %q:\n%v", header, rendered)
+		}
+	}
+
+	if tree := renderPlain(t, explain, Options{Style: StyleTree}); tree != renderPlain(t, explain, Options{}) {
+		t.Errorf("StyleTree renders differently from the default style")
+	}
+}
+
+func TestShowEstimates(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 5000, "Actual Total Time": 1, "Actual Rows": 0, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "(est ") {
+		t.Errorf("estimates shown without ShowEstimates:\n%v", rendered)
+	}
+
+	rendered := renderPlain(t, explain, Options{ShowEstimates: true, HideZeroMetrics: true})
+
+	if !strings.Contains(rendered, "Rows: 0 (est 5,000)") {
+		t.Errorf("output missing the estimate next to zero actual rows:\n%v", rendered)
+	}
+}
+
+func TestRecheckCondition(t *testing.T) {
+	scan := func(removed int) string {
+		return fmt.Sprintf(`[{"Plan": {"Node Type": "Bitmap Heap Scan", "Relation Name": "t", "Schema": "public", "Recheck Cond": "(a = 1)", "Rows Removed by Index Recheck": %d, "Total Cost": 10, "Plan Rows": 10, "Actual Total Time": 1, "Actual Rows": 10, "Actual Loops": 1}, "Execution Time": 1}]`, removed)
+	}
+
+	tests := []struct {
+		removed int
+		want    string
+	}{
+		{1500, "recheck (a = 1) [-1,500 rows]\n"},
+		{0, "recheck (a = 1)\n"},
+	}
+
+	for _, test := range tests {
+		if rendered := renderPlain(t, analyzeOne(t, scan(test.removed)), Options{}); !strings.Contains(rendered, test.want) {
+			t.Errorf("output missing %q:\n%v", test.want, rendered)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	rendered := renderPlain(t, explain, Options{Annotations: map[int]string{3: "called once per outer row\nconsider a join", 9: "no such node"}})
+
+	var annotated []string
+
+	for _, line := range strings.Split(rendered, "\n") {
+		if index := strings.Index(line, "# "); index >= 0 && !strings.Contains(line, "─⌠") {
+			annotated = append(annotated, line[index:])
+		}
+	}
+
+	if len(annotated) != 2 || annotated[0] != "# called once per outer row" || annotated[1] != "# consider a join" {
+		t.Errorf("annotation lines = %q:\n%v", annotated, rendered)
+	}
+
+	if strings.Index(rendered, "# called once") < strings.Index(rendered, "#3 inner Function Scan") {
+		t.Errorf("annotation is not under node #3:\n%v", rendered)
+	}
+}
+
+func TestNodeByID(t *testing.T) {
+	explain := Explain{Plan: Plan{NodeType: NestedLoop, Plans: []Plan{
+		{NodeType: Hash, Plans: []Plan{{NodeType: SequenceScan}}},
+		{NodeType: IndexScan, Plans: []Plan{{NodeType: BitmapIndexScan}}},
+	}}}
+
+	ProcessExplain(&explain)
+
+	want := map[int]NodeType{1: NestedLoop, 2: Hash, 3: SequenceScan, 4: IndexScan, 5: BitmapIndexScan}
+
+	for id, nodeType := range want {
+		if plan := NodeByID(&explain, id); plan == nil || plan.ID != id || plan.NodeType != nodeType {
+			t.Errorf("NodeByID(%d) = %v, want %v", id, plan, nodeType)
+		}
+	}
+
+	for _, id := range []int{0, -1, 6} {
+		if plan := NodeByID(&explain, id); plan != nil {
+			t.Errorf("NodeByID(%d) = #%d, want nil", id, plan.ID)
+		}
+	}
+}
+
+func TestCalculateLoopCosts(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	if got := explain.Plan.Plans[1].LoopCost; got != 50 {
+		t.Errorf("inner LoopCost = %v, want 0.5 × 100 = 50", got)
+	}
+
+	if got := explain.Plan.Plans[0].LoopCost; got != 0 {
+		t.Errorf("outer LoopCost = %v, want 0", got)
+	}
+
+	estimated := analyzeOne(t, `[{"Plan": {"Node Type": "Nested Loop", "Total Cost": 100, "Plan Rows": 10,
+		"Plans": [
+			{"Node Type": "Index Scan", "Parent Relationship": "Inner", "Relation Name": "b", "Schema": "public", "Index Name": "b_pkey", "Total Cost": 0.5, "Plan Rows": 1},
+			{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "a", "Schema": "public", "Total Cost": 50, "Plan Rows": 40}]}}]`)
+
+	if got := estimated.Plan.Plans[0].LoopCost; got != 20 {
+		t.Errorf("estimated inner LoopCost = %v, want 0.5 × 40 = 20", got)
+	}
+
+	if rendered := renderPlain(t, estimated, Options{}); !strings.Contains(rendered, "cost 0.5 × 40 loops = 20") {
+		t.Errorf("output missing the loop cost:\n%v", rendered)
+	}
+}
+
+func TestTriage(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Sort", "Sort Key": ["t.a"], "Sort Method": "quicksort", "Sort Space Used": 25, "Sort Space Type": "Memory", "Total Cost": 100, "Plan Rows": 10, "Actual Total Time": 6, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Index Scan", "Parent Relationship": "Outer", "Relation Name": "t", "Schema": "public", "Alias": "x", "Index Name": "t_b_idx", "Index Cond": "(b = 1)", "Filter": "(c > 2)", "Rows Removed by Filter": 5, "Total Cost": 90, "Plan Rows": 10, "Actual Total Time": 3, "Actual Rows": 10, "Actual Loops": 1}]},
+		"Execution Time": 6}]`)
+
+	full := renderPlain(t, explain, Options{})
+	triage := renderPlain(t, explain, Options{Triage: true})
+
+	for _, line := range []string{"on public.t (x)", "using t_b_idx", "sort by t.a", Descriptions[Sort][:20]} {
+		if !strings.Contains(full, line) {
+			t.Errorf("full output missing %q:\n%v", line, full)
+		}
+
+		if strings.Contains(triage, line) {
+			t.Errorf("triage output contains %q:\n%v", line, triage)
+		}
+	}
+
+	for _, line := range []string{"on t\n", "filter (c > 2)", "condition (b = 1)"} {
+		if !strings.Contains(triage, line) {
+			t.Errorf("triage output missing %q:\n%v", line, triage)
+		}
+	}
+}
+
+func TestSubplanName(t *testing.T) {
+	explain := analyzeFile(t, "initplan.json")
+
+	if explain.Generic {
+		t.Error("plan referring to an InitPlan result is flagged as generic")
+	}
+
+	if name := explain.Plan.Plans[0].SubplanName; name != "InitPlan 1 (returns $0)" {
+		t.Errorf("SubplanName = %q", name)
+	}
+
+	if out := renderPlain(t, explain, Options{}); !strings.Contains(out, "InitPlan 1 (returns $0)\n") {
+		t.Errorf("output missing subplan name:\n%v", out)
+	}
+
+	generic := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Filter": "(a = $1)", "Total Cost": 10, "Plan Rows": 1,
+		"Plans": [{"Node Type": "Result", "Parent Relationship": "InitPlan", "Subplan Name": "InitPlan 1 (returns $0)", "Total Cost": 0.01, "Plan Rows": 1}]}}]`)
+
+	if !generic.Generic {
+		t.Error("plan referring to a parameter no subplan returns is not flagged as generic")
+	}
+}
+
+func TestMergeJoinConditions(t *testing.T) {
+	explain := analyzeFile(t, "mergejoin.json")
+	plan := explain.Plan
+
+	if plan.MergeCondition != "(o.customer_id = c.id)" || plan.JoinFilter != "(o.total > c.credit_limit)" || plan.RowsRemovedByJoinFilter != 37907 {
+		t.Errorf("merge join fields = %q, %q, %v", plan.MergeCondition, plan.JoinFilter, plan.RowsRemovedByJoinFilter)
+	}
+
+	out := renderPlain(t, explain, Options{})
+
+	for _, line := range []string{"on (o.customer_id = c.id)\n", "join filter (o.total > c.credit_limit) [-37,907 rows]\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output missing %q:\n%v", line, out)
+		}
+	}
+
+	generic := analyzeOne(t, `[{"Plan": {"Node Type": "Nested Loop", "Join Type": "Inner", "Join Filter": "(a.id = $1)", "Total Cost": 10, "Plan Rows": 1}}]`)
+
+	if !generic.Generic {
+		t.Error("parameter in a join filter does not flag the plan as generic")
+	}
+}
+
+func TestStartupCostSplit(t *testing.T) {
+	sort := func(startup, total float64) string {
+		return fmt.Sprintf(`[{"Plan": {"Node Type": "Sort", "Sort Key": ["t.a"], "Startup Cost": %v, "Total Cost": %v, "Plan Rows": 100, "Actual Total Time": 4, "Actual Rows": 100, "Actual Loops": 1,
+			"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Startup Cost": 0, "Total Cost": 10, "Plan Rows": 100, "Actual Total Time": 1, "Actual Rows": 100, "Actual Loops": 1}]},
+			"Execution Time": 4}]`, startup, total)
+	}
+
+	explain := analyzeOne(t, sort(1310.84, 1400))
+
+	if !HasSignificantStartupCost(&explain.Plan) {
+		t.Error("Sort spending 94% of its cost up front has no significant startup cost")
+	}
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "startup cost 1,310.84 run cost 89.16\n") {
+		t.Errorf("output missing the startup and run cost split:\n%v", rendered)
+	}
+
+	tests := []struct {
+		startup, total float64
+		want           bool
+	}{
+		{49.99, 100, false},
+		{50, 100, true},
+		{100, 100, true},
+		{0, 0, false},
+		{120, 100, false},
+	}
+
+	for _, test := range tests {
+		plan := Plan{NodeType: Sort, StartupCost: test.startup, TotalCost: test.total}
+
+		if got := HasSignificantStartupCost(&plan); got != test.want {
+			t.Errorf("HasSignificantStartupCost(%v of %v) = %v, want %v", test.startup, test.total, got, test.want)
+		}
+	}
+
+	if rendered := renderPlain(t, analyzeOne(t, sort(49.99, 100)), Options{}); strings.Contains(rendered, "startup cost") {
+		t.Errorf("split shown below StartupCostShare:\n%v", rendered)
+	}
+
+	share := StartupCostShare
+	defer func() { StartupCostShare = share }()
+
+	StartupCostShare = 0.4
+
+	if rendered := renderPlain(t, analyzeOne(t, sort(49.99, 100)), Options{}); !strings.Contains(rendered, "startup cost 49.99 run cost 50.01\n") {
+		t.Errorf("split not shown above a lowered StartupCostShare:\n%v", rendered)
+	}
+}
@@ -0,0 +1,37 @@
+package gopev
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A GroupingSet is one entry of an Aggregate's "Grouping Sets" (GROUPING
+// SETS, ROLLUP or CUBE). Sorted sets share a sort and list their Group Keys;
+// hashed ones list Hash Keys.
+type GroupingSet struct {
+	SortKey   []string   `json:"Sort Key"`
+	GroupKeys [][]string `json:"Group Keys"`
+	HashKeys  [][]string `json:"Hash Keys"`
+}
+
+func formatKeySets(sets [][]string) string {
+	var formatted []string
+
+	for _, keys := range sets {
+		formatted = append(formatted, "("+strings.Join(keys, ", ")+")")
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func formatGroupingSet(set GroupingSet) string {
+	if len(set.HashKeys) > 0 {
+		return fmt.Sprintf("%v %v", formatKeySets(set.HashKeys), MutedFormat("hashed"))
+	}
+
+	if len(set.SortKey) > 0 {
+		return fmt.Sprintf("%v %v %v", formatKeySets(set.GroupKeys), MutedFormat("sorted by"), strings.Join(set.SortKey, ", "))
+	}
+
+	return formatKeySets(set.GroupKeys)
+}
@@ -0,0 +1,41 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatGroupingSet(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	tests := []struct {
+		set  GroupingSet
+		want string
+	}{
+		{GroupingSet{SortKey: []string{"a", "b"}, GroupKeys: [][]string{{"a", "b"}, {"a"}, {}}}, "(a, b), (a), () sorted by a, b"},
+		{GroupingSet{HashKeys: [][]string{{"c"}}}, "(c) hashed"},
+		{GroupingSet{GroupKeys: [][]string{{}}}, "()"},
+	}
+
+	for _, test := range tests {
+		if got := formatGroupingSet(test.set); got != test.want {
+			t.Errorf("formatGroupingSet(%+v) = %q, want %q", test.set, got, test.want)
+		}
+	}
+}
+
+func TestGroupingSetsOutput(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Aggregate", "Strategy": "Mixed", "Total Cost": 10, "Plan Rows": 10, "Actual Total Time": 1, "Actual Rows": 10, "Actual Loops": 1,
+		"Grouping Sets": [{"Sort Key": ["a"], "Group Keys": [["a"], []]}, {"Hash Keys": [["b"]]}]}, "Execution Time": 1}]`)
+
+	rendered := renderPlain(t, explain, Options{})
+
+	for _, want := range []string{"grouping sets (a), () sorted by a", "grouping sets (b) hashed"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package gopev
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A Hint inspects a processed node and returns an advisory message, or an
+// empty string when it has nothing to say.
+type Hint func(explain *Explain, plan *Plan) string
+
+var Hints = []Hint{
+	WideRowHint,
+	SortSpillHint,
+	HashJoinSidesHint,
+	SerialParallelHint,
+	SortedAggregateHint,
+	StorageSpillHint,
+	HashAggSpillHint,
+	MergeJoinMaterializeHint,
+	IndexOnlyHint,
+	RedundantSortHint,
+	MissingStatisticsHint,
+	DuplicateScanHint,
+	MemoizeHint,
+	SingleCopyHint,
+	SkewedAppendHint,
+}
+
+var WideRowThreshold uint64 = 64 * 1024 * 1024
+
+var HashJoinSidesRatio float64 = 10
+
+var IndexOnlyBlocks uint64 = 1000
+
+// SkewedAppendShare is the share of an Append's time, or rows when there are
+// no timings, above which SkewedAppendHint calls one of at least
+// SkewedAppendChildren children dominant.
+var SkewedAppendShare float64 = 0.8
+
+var SkewedAppendChildren = 3
+
+// SerialPlanCost and SerialScanRows are the total cost and the rows read by
+// a single sequential scan above which SerialPlanHint expects a parallel plan.
+var SerialPlanCost float64 = 100000
+
+var SerialScanRows uint64 = 1000000
+
+// DefaultRowEstimates are row counts the planner falls back to when a table
+// has never been analyzed: 1000 for functions and the counts its ten-page
+// guess gives for common row widths.
+var DefaultRowEstimates = []uint64{1000, 1360, 1700, 2040, 2550}
+
+func PlanHints(explain *Explain, plan *Plan) []string {
+	return planHints(explain, plan, Options{})
+}
+
+// planHints runs Hints and then the opt-in hints enabled in options.
+func planHints(explain *Explain, plan *Plan, options Options) []string {
+	var hints []string
+
+	enabled := Hints[:len(Hints):len(Hints)]
+
+	if options.CastHint {
+		enabled = append(enabled, CastHint)
+	}
+
+	if options.SerialPlanHint {
+		enabled = append(enabled, SerialPlanHint)
+	}
+
+	for _, hint := range enabled {
+		if message := hint(explain, plan); message != "" {
+			hints = append(hints, message)
+		}
+	}
+
+	return hints
+}
+
+func WideRowHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != Sort && plan.NodeType != Hash && plan.NodeType != Materialize {
+		return ""
+	}
+
+	size := plan.PlanWidth * plan.ActualRows
+
+	if size < WideRowThreshold {
+		return ""
+	}
+
+	return fmt.Sprintf("holds ~%v of %v-byte rows, which may spill or exhaust memory", FormatBytes(size), plan.PlanWidth)
+}
+
+func SortSpillHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != Sort && plan.NodeType != IncrementalSort {
+		return ""
+	}
+
+	detail := GetSortDetail(plan)
+
+	if detail.SpaceType != "Disk" {
+		return ""
+	}
+
+	return fmt.Sprintf("sort spilled %v to disk, consider raising work_mem", FormatBytes(detail.SpaceUsed*1024))
+}
+
+func HashJoinSidesHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != HashJoin || len(plan.Plans) != 2 {
+		return ""
+	}
+
+	probe, build := &plan.Plans[0], &plan.Plans[1]
+
+	if probe.ParentRelationship == "Inner" {
+		probe, build = build, probe
+	}
+
+	if build.ActualRows < 1000 || float64(build.ActualRows) < float64(probe.ActualRows)*HashJoinSidesRatio {
+		return ""
+	}
+
+	return fmt.Sprintf("hashes %v rows to probe with only %v, hashing the smaller side is usually cheaper", FormatInteger(int64(build.ActualRows)), FormatInteger(int64(probe.ActualRows)))
+}
+
+func SerialParallelHint(explain *Explain, plan *Plan) string {
+	if !plan.ParallelAware || !plan.Parallel || plan.GatherWorkersLaunched > 0 || explain.EstimateOnly {
+		return ""
+	}
+
+	return "parallel aware but ran serially, no workers were launched (check max_parallel_workers)"
+}
+
+func SortedAggregateHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != Aggregate || plan.Strategy != "Sorted" || len(plan.Plans) == 0 {
+		return ""
+	}
+
+	child := &plan.Plans[0]
+
+	if child.NodeType != Sort {
+		return ""
+	}
+
+	return fmt.Sprintf("groups the output of a sort of %v rows, a hashed aggregate might avoid the sort (check work_mem)", FormatInteger(int64(child.ActualRows)))
+}
+
+func StorageSpillHint(explain *Explain, plan *Plan) string {
+	if plan.Storage != "Disk" {
+		return ""
+	}
+
+	return fmt.Sprintf("spilled %v to disk, consider raising work_mem", FormatBytes(plan.MaximumStorage*1024))
+}
+
+func HashAggSpillHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != Aggregate || plan.DiskUsage == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("hash aggregate spilled %v to disk in %v batches, consider raising hash_mem_multiplier", FormatBytes(plan.DiskUsage*1024), FormatInteger(int64(plan.HashAggBatches)))
+}
+
+func MergeJoinMaterializeHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != MergeJoin {
+		return ""
+	}
+
+	for _, child := range plan.Plans {
+		if child.NodeType != Materialize {
+			continue
+		}
+
+		var usage []string
+
+		if child.Storage != "" {
+			usage = append(usage, fmt.Sprintf("%v %v", FormatBytes(child.MaximumStorage*1024), strings.ToLower(child.Storage)))
+		}
+
+		if temp := child.TempReadBlocks + child.TempWrittenBlocks; temp > 0 {
+			usage = append(usage, fmt.Sprintf("%v of temp I/O", FormatBytes(temp*8192)))
+		}
+
+		if len(usage) == 0 {
+			usage = append(usage, fmt.Sprintf("%v rows", FormatInteger(int64(child.ActualRows))))
+		}
+
+		return fmt.Sprintf("inner side is materialized to be re-read (%v), a large inner input puts pressure on work_mem", strings.Join(usage, ", "))
+	}
+
+	return ""
+}
+
+// IndexOnlyHint looks at the blocks an Index Scan reads in each of its
+// loops, since the counts EXPLAIN reports add up every loop and the inner
+// side of a nested loop would otherwise pass IndexOnlyBlocks on loops alone.
+func IndexOnlyHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != IndexScan {
+		return ""
+	}
+
+	blocks := plan.SharedHitBlocks + plan.SharedReadBlocks
+	read := "reads " + FormatInteger(int64(blocks)) + " blocks"
+
+	if plan.ActualLoops > 1 {
+		blocks = blocks / plan.ActualLoops
+		read = "reads " + FormatInteger(int64(blocks)) + " blocks per loop"
+	}
+
+	if blocks < IndexOnlyBlocks {
+		return ""
+	}
+
+	if output := NonEmptyOutput(plan); len(output) > 0 {
+		if len(output) > 3 {
+			return ""
+		}
+
+		return fmt.Sprintf("%v to return only %v, an index covering these columns (INCLUDE) could allow an Index Only Scan", read, strings.Join(output, ", "))
+	}
+
+	if plan.PlanWidth == 0 || plan.PlanWidth > 16 {
+		return ""
+	}
+
+	return fmt.Sprintf("%v to return %v-byte rows, an index covering the columns used could allow an Index Only Scan", read, plan.PlanWidth)
+}
+
+func RedundantSortHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != Sort || len(plan.Plans) != 1 || len(plan.SortKey) == 0 {
+		return ""
+	}
+
+	child := &plan.Plans[0]
+
+	if child.NodeType != IndexScan && child.NodeType != IndexOnlyScan {
+		return ""
+	}
+
+	if child.ScanDirection != "Forward" && child.ScanDirection != "Backward" {
+		return ""
+	}
+
+	key := strings.Fields(plan.SortKey[0])
+	if len(key) == 0 {
+		return ""
+	}
+
+	column := key[0]
+	if dot := strings.LastIndex(column, "."); dot >= 0 {
+		column = column[dot+1:]
+	}
+
+	if !identifierPattern.MatchString(column) || column != leadingIndexColumn(child) {
+		return ""
+	}
+
+	return fmt.Sprintf("sorts the ordered output of %v on %v, if the index already provides this order the sort is redundant", child.IndexName, column)
+}
+
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// leadingIndexColumn is the column a single-column index is on, read from
+// the name PostgreSQL gives an index by default, <table>_<column>_idx or
+// <table>_<column>_key. EXPLAIN does not show an index's columns, so it is
+// empty for any other name.
+func leadingIndexColumn(plan *Plan) string {
+	name := strings.TrimPrefix(plan.IndexName, plan.RelationName+"_")
+
+	if plan.RelationName == "" || name == plan.IndexName {
+		return ""
+	}
+
+	for _, suffix := range []string{"_idx", "_key"} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+
+	return ""
+}
+
+func MissingStatisticsHint(explain *Explain, plan *Plan) string {
+	if plan.RelationName == "" || plan.PlannerRowEstimateFactor < 10 || explain.EstimateOnly {
+		return ""
+	}
+
+	for _, estimate := range DefaultRowEstimates {
+		if plan.PlanRows == estimate {
+			return fmt.Sprintf("estimate of %v rows looks like a default, statistics on %v may be missing, try ANALYZE %v.%v", FormatInteger(int64(plan.PlanRows)), plan.RelationName, plan.Schema, plan.RelationName)
+		}
+	}
+
+	return ""
+}
+
+// DuplicateScanHint reports, on the first scan of a relation, every other
+// scan of the same relation in the plan.
+func DuplicateScanHint(explain *Explain, plan *Plan) string {
+	if plan.RelationName == "" || plan.NodeType == ModifyTable {
+		return ""
+	}
+
+	var scans []*Plan
+
+	CollectPlans(&explain.Plan, func(other *Plan, path []*Plan) {
+		if other.NodeType != ModifyTable && other.Schema == plan.Schema && other.RelationName == plan.RelationName {
+			scans = append(scans, other)
+		}
+	})
+
+	if len(scans) < 2 || scans[0] != plan {
+		return ""
+	}
+
+	var ids []string
+	var duration float64
+
+	for _, scan := range scans {
+		ids = append(ids, fmt.Sprintf("#%d", scan.ID))
+		duration += scan.ActualDuration
+	}
+
+	message := fmt.Sprintf("%v.%v is scanned %v times (%v)", plan.Schema, plan.RelationName, len(scans), strings.Join(ids, ", "))
+
+	if !explain.EstimateOnly {
+		message += fmt.Sprintf(" taking %v in total", DurationToString(duration))
+	}
+
+	return message + ", consolidating the scans may help"
+}
+
+func MemoizeHint(explain *Explain, plan *Plan) string {
+	lookups := plan.CacheHits + plan.CacheMisses
+
+	if plan.NodeType != Memoize || lookups < MemoizeLookups {
+		return ""
+	}
+
+	if ratio := MemoizeHitRate(plan); ratio < MemoizeHitRatio {
+		return fmt.Sprintf("cache answered only %.0f%% of %v lookups, the join may be cheaper without it (check the n_distinct estimate of the cache key)", ratio*100, FormatInteger(int64(lookups)))
+	}
+
+	if plan.CacheEvictions > 0 && float64(plan.CacheEvictions) >= float64(plan.CacheMisses)*MemoizeEvictionRatio {
+		return fmt.Sprintf("cache evicted %v entries as it ran out of memory, consider raising work_mem or hash_mem_multiplier", FormatInteger(int64(plan.CacheEvictions)))
+	}
+
+	return ""
+}
+
+func SingleCopyHint(explain *Explain, plan *Plan) string {
+	if plan.NodeType != Gather || !plan.SingleCopy {
+		return ""
+	}
+
+	return "single copy: the plan below ran in one worker without parallelism, as debug_parallel_query (force_parallel_mode before PostgreSQL 16) forces, which should be off outside testing"
+}
+
+func SkewedAppendHint(explain *Explain, plan *Plan) string {
+	if (plan.NodeType != Append && plan.NodeType != MergeAppend) || len(plan.Plans) < SkewedAppendChildren {
+		return ""
+	}
+
+	timed := !explain.EstimateOnly && !explain.TimingOff
+	measure := func(child *Plan) float64 {
+		if explain.EstimateOnly {
+			return float64(child.PlanRows)
+		} else if timed {
+			return child.ActualTotalTime * float64(child.ActualLoops)
+		}
+		return float64(child.ActualRows * child.ActualLoops)
+	}
+
+	var total, largest float64
+	var dominant *Plan
+
+	for index, _ := range plan.Plans {
+		child := &plan.Plans[index]
+		value := measure(child)
+		total += value
+
+		if value > largest {
+			largest, dominant = value, child
+		}
+	}
+
+	if total <= 0 || largest/total < SkewedAppendShare {
+		return ""
+	}
+
+	node := fmt.Sprintf("#%d", dominant.ID)
+	if dominant.RelationName != "" {
+		node += fmt.Sprintf(" on %v.%v", dominant.Schema, dominant.RelationName)
+	}
+
+	what := "rows"
+	if timed {
+		what = "time"
+	}
+
+	return fmt.Sprintf("%v accounts for %.0f%% of the %v of its %v children, the partitioning is not spreading the load", node, largest/total*100, what, len(plan.Plans))
+}
+
+var columnCastPattern = regexp.MustCompile(`\(([A-Za-z_][\w.]*)\)::([A-Za-z][\w ]*)`)
+
+// CastHint flags scans whose filter or index condition casts a column, e.g.
+// ((code)::text = '42'::text), which usually means an index on that column
+// cannot be used. It is a heuristic and not in Hints; set Options.CastHint
+// to enable it.
+func CastHint(explain *Explain, plan *Plan) string {
+	if plan.RelationName == "" {
+		return ""
+	}
+
+	for _, condition := range []string{plan.Filter, plan.IndexCondition, plan.RecheckCondition} {
+		if match := columnCastPattern.FindStringSubmatch(condition); match != nil {
+			return fmt.Sprintf("casts %v to %v, which can stop an index on %v from being used, compare it with a value of its own type", match[1], strings.TrimSpace(match[2]), match[1])
+		}
+	}
+
+	return ""
+}
+
+// SerialPlanHint flags, on the root node, an expensive plan that runs
+// entirely serially though it sequentially scans a large table. The plan
+// cannot tell why; settings such as max_parallel_workers_per_gather or a
+// parallel-unsafe function in the query are common causes. It is not in
+// Hints; set Options.SerialPlanHint to enable it.
+func SerialPlanHint(explain *Explain, plan *Plan) string {
+	if plan != &explain.Plan || plan.NodeType == ModifyTable || plan.TotalCost < SerialPlanCost {
+		return ""
+	}
+
+	var largest *Plan
+	var rows uint64
+	parallel := false
+
+	CollectPlans(plan, func(node *Plan, path []*Plan) {
+		if node.NodeType == Gather || node.NodeType == GatherMerge || node.Parallel || node.ParallelAware {
+			parallel = true
+		}
+
+		if node.NodeType != SequenceScan {
+			return
+		}
+
+		scanned := node.PlanRows
+		if !explain.EstimateOnly {
+			scanned = (node.ActualRows + node.RowsRemovedByFilter) * node.ActualLoops
+		}
+
+		if scanned > rows {
+			largest, rows = node, scanned
+		}
+	})
+
+	if parallel || largest == nil || rows < SerialScanRows {
+		return ""
+	}
+
+	return fmt.Sprintf("runs serially at a cost of %v though #%d scans %v rows of %v, check the parallel settings and whether the query calls parallel-unsafe functions", FormatCost(plan.TotalCost), largest.ID, FormatInteger(int64(rows)), largest.RelationName)
+}
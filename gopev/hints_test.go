@@ -0,0 +1,394 @@
+package gopev
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedundantSortHint(t *testing.T) {
+	tests := []struct {
+		key   string
+		index string
+		want  bool
+	}{
+		{"e.created_at DESC", "events_created_at_idx", true},
+		{"created_at", "events_created_at_key", true},
+		{"e.created", "events_created_at_idx", false},
+		{"e.at", "events_created_at_idx", false},
+		{"e.created_at", "events_created_at_user_id_idx", false},
+		{"e.created_at", "events_by_time", false},
+		{"e.created_at", "events_pkey", false},
+		{"(lower(e.created_at))", "events_created_at_idx", false},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{NodeType: Sort, SortKey: []string{test.key}, Plans: []Plan{
+			{NodeType: IndexScan, ScanDirection: "Forward", IndexName: test.index, RelationName: "events"},
+		}}
+
+		if got := RedundantSortHint(&Explain{}, plan) != ""; got != test.want {
+			t.Errorf("RedundantSortHint(%q on %v) = %v, want %v", test.key, test.index, got, test.want)
+		}
+	}
+}
+
+func TestIndexOnlyHintPerLoop(t *testing.T) {
+	tests := []struct {
+		blocks uint64
+		loops  uint64
+		want   string
+	}{
+		{5000, 1, "reads 5,000 blocks to return only o.total, an index covering these columns (INCLUDE) could allow an Index Only Scan"},
+		{5000, 1000, ""},
+		{500, 1, ""},
+		{4000000, 2000, "reads 2,000 blocks per loop to return only o.total, an index covering these columns (INCLUDE) could allow an Index Only Scan"},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{NodeType: IndexScan, SharedHitBlocks: test.blocks, ActualLoops: test.loops, Output: []string{"o.total"}}
+
+		if got := IndexOnlyHint(&Explain{}, plan); got != test.want {
+			t.Errorf("IndexOnlyHint(%v blocks, %v loops) = %q, want %q", test.blocks, test.loops, got, test.want)
+		}
+	}
+}
+
+func TestOptInHints(t *testing.T) {
+	buffer, err := os.ReadFile("testdata/serial.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explain := analyzeOne(t, string(buffer))
+	hints := len(Hints)
+
+	if got := planHints(explain, &explain.Plan, Options{}); len(got) != 0 {
+		t.Errorf("hints without SerialPlanHint = %q, want none", got)
+	}
+
+	if got := planHints(explain, &explain.Plan, Options{SerialPlanHint: true}); len(got) != 1 {
+		t.Errorf("hints with SerialPlanHint = %q, want one", got)
+	}
+
+	if len(Hints) != hints {
+		t.Errorf("Hints grew from %d to %d", hints, len(Hints))
+	}
+
+	if PlanScore(explain) != planScore(explain, Options{CastHint: true}) {
+		t.Errorf("CastHint changed the score of a plan without casts")
+	}
+
+	if collectWarnings(explain, Options{SerialPlanHint: true})[0].Kind != HintWarning {
+		t.Errorf("SerialPlanHint not reported as %v", HintWarning)
+	}
+}
+
+func TestCastHint(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want bool
+	}{
+		{Plan{NodeType: SequenceScan, RelationName: "t", Filter: "((code)::text = '42'::text)"}, true},
+		{Plan{NodeType: IndexScan, RelationName: "t", IndexCondition: "((id)::bigint = 42)"}, true},
+		{Plan{NodeType: SequenceScan, RelationName: "t", Filter: "(code = '42'::text)"}, false},
+	}
+
+	for _, test := range tests {
+		if got := CastHint(&Explain{}, &test.plan) != ""; got != test.want {
+			t.Errorf("CastHint(%+v) = %v, want %v", test.plan, got, test.want)
+		}
+	}
+}
+
+func TestWideRowHint(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want string
+	}{
+		{Plan{NodeType: Sort, PlanWidth: 2048, ActualRows: 40000}, "holds ~82 MB of 2048-byte rows, which may spill or exhaust memory"},
+		{Plan{NodeType: Hash, PlanWidth: 1024, ActualRows: 65536}, "holds ~67 MB of 1024-byte rows, which may spill or exhaust memory"},
+		{Plan{NodeType: Materialize, PlanWidth: 16, ActualRows: 1000}, ""},
+		{Plan{NodeType: SequenceScan, PlanWidth: 2048, ActualRows: 40000}, ""},
+	}
+
+	for _, test := range tests {
+		if got := WideRowHint(&Explain{}, &test.plan); got != test.want {
+			t.Errorf("WideRowHint(%v %v × %v) = %q, want %q", test.plan.NodeType, test.plan.ActualRows, test.plan.PlanWidth, got, test.want)
+		}
+	}
+}
+
+func TestHashJoinSidesHint(t *testing.T) {
+	join := func(probe, build uint64) *Plan {
+		return &Plan{NodeType: HashJoin, Plans: []Plan{
+			{NodeType: SequenceScan, ParentRelationship: "Outer", ActualRows: probe},
+			{NodeType: Hash, ParentRelationship: "Inner", ActualRows: build},
+		}}
+	}
+
+	tests := []struct {
+		plan *Plan
+		want string
+	}{
+		{join(100, 50000), "hashes 50,000 rows to probe with only 100, hashing the smaller side is usually cheaper"},
+		{join(100, 900), ""},
+		{join(10000, 50000), ""},
+		{join(50000, 100), ""},
+		{&Plan{NodeType: MergeJoin}, ""},
+	}
+
+	for _, test := range tests {
+		if got := HashJoinSidesHint(&Explain{}, test.plan); got != test.want {
+			t.Errorf("HashJoinSidesHint(%v) = %q, want %q", test.plan.NodeType, got, test.want)
+		}
+	}
+
+	swapped := join(100, 50000)
+	swapped.Plans[0], swapped.Plans[1] = swapped.Plans[1], swapped.Plans[0]
+
+	if HashJoinSidesHint(&Explain{}, swapped) == "" {
+		t.Errorf("HashJoinSidesHint missed the build side listed first")
+	}
+}
+
+func TestSerialParallelHint(t *testing.T) {
+	gather := func(launched uint64) Explain {
+		return Explain{Plan: Plan{NodeType: Gather, WorkersPlanned: 2, WorkersLaunched: launched, ActualLoops: 1, Plans: []Plan{
+			{NodeType: SequenceScan, ParallelAware: true, ActualLoops: 1, Plans: []Plan{
+				{NodeType: SequenceScan, ActualLoops: 1},
+			}},
+		}}}
+	}
+
+	tests := []struct {
+		launched uint64
+		want     bool
+	}{
+		{0, true},
+		{2, false},
+	}
+
+	for _, test := range tests {
+		explain := gather(test.launched)
+		CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) { PropagateParallelism(plan) })
+
+		scan := &explain.Plan.Plans[0]
+
+		if !scan.Parallel || !scan.Plans[0].Parallel || scan.Plans[0].GatherWorkersLaunched != test.launched {
+			t.Errorf("PropagateParallelism did not reach the nodes below Gather: %+v", scan)
+		}
+
+		if got := SerialParallelHint(&explain, scan) != ""; got != test.want {
+			t.Errorf("SerialParallelHint with %v workers launched = %v, want %v", test.launched, got, test.want)
+		}
+	}
+
+	if got := SerialParallelHint(&Explain{}, &Plan{NodeType: SequenceScan, ParallelAware: true}); got != "" {
+		t.Errorf("SerialParallelHint outside a Gather = %q, want none", got)
+	}
+}
+
+func TestSortedAggregateHint(t *testing.T) {
+	aggregate := func(strategy string, child NodeType) *Plan {
+		return &Plan{NodeType: Aggregate, Strategy: strategy, Plans: []Plan{{NodeType: child, ActualRows: 250000}}}
+	}
+
+	tests := []struct {
+		plan *Plan
+		want string
+	}{
+		{aggregate("Sorted", Sort), "groups the output of a sort of 250,000 rows, a hashed aggregate might avoid the sort (check work_mem)"},
+		{aggregate("Sorted", IndexScan), ""},
+		{aggregate("Hashed", Sort), ""},
+		{&Plan{NodeType: Aggregate, Strategy: "Sorted"}, ""},
+	}
+
+	for _, test := range tests {
+		if got := SortedAggregateHint(&Explain{}, test.plan); got != test.want {
+			t.Errorf("SortedAggregateHint(%v with %d children) = %q, want %q", test.plan.Strategy, len(test.plan.Plans), got, test.want)
+		}
+	}
+}
+
+func TestStorageSpillHint(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want string
+	}{
+		{Plan{NodeType: WindowAgg, Storage: "Disk", MaximumStorage: 2048}, "spilled 2.1 MB to disk, consider raising work_mem"},
+		{Plan{NodeType: WindowAgg, Storage: "Memory", MaximumStorage: 2048}, ""},
+		{Plan{NodeType: WindowAgg}, ""},
+	}
+
+	for _, test := range tests {
+		if got := StorageSpillHint(&Explain{}, &test.plan); got != test.want {
+			t.Errorf("StorageSpillHint(%q) = %q, want %q", test.plan.Storage, got, test.want)
+		}
+	}
+}
+
+func TestMergeJoinMaterializeHint(t *testing.T) {
+	join := func(inner Plan) *Plan {
+		inner.NodeType = Materialize
+		return &Plan{NodeType: MergeJoin, Plans: []Plan{{NodeType: IndexScan}, inner}}
+	}
+
+	tests := []struct {
+		plan *Plan
+		want string
+	}{
+		{join(Plan{Storage: "Memory", MaximumStorage: 512}), "inner side is materialized to be re-read (524 kB memory), a large inner input puts pressure on work_mem"},
+		{join(Plan{TempReadBlocks: 100, TempWrittenBlocks: 100}), "inner side is materialized to be re-read (1.6 MB of temp I/O), a large inner input puts pressure on work_mem"},
+		{join(Plan{ActualRows: 12000}), "inner side is materialized to be re-read (12,000 rows), a large inner input puts pressure on work_mem"},
+		{&Plan{NodeType: MergeJoin, Plans: []Plan{{NodeType: IndexScan}, {NodeType: Sort}}}, ""},
+		{&Plan{NodeType: NestedLoop, Plans: []Plan{{NodeType: IndexScan}, {NodeType: Materialize}}}, ""},
+	}
+
+	for _, test := range tests {
+		if got := MergeJoinMaterializeHint(&Explain{}, test.plan); got != test.want {
+			t.Errorf("MergeJoinMaterializeHint(%v) = %q, want %q", test.plan.NodeType, got, test.want)
+		}
+	}
+}
+
+func TestMissingStatisticsHint(t *testing.T) {
+	scan := func(planRows uint64, factor float64) *Plan {
+		return &Plan{NodeType: SequenceScan, Schema: "public", RelationName: "events", PlanRows: planRows, PlannerRowEstimateFactor: factor}
+	}
+
+	tests := []struct {
+		plan *Plan
+		want string
+	}{
+		{scan(2550, 40), "estimate of 2,550 rows looks like a default, statistics on events may be missing, try ANALYZE public.events"},
+		{scan(2550, 2), ""},
+		{scan(2551, 40), ""},
+		{&Plan{NodeType: "Function Scan", PlanRows: 1000, PlannerRowEstimateFactor: 40}, ""},
+	}
+
+	for _, test := range tests {
+		if got := MissingStatisticsHint(&Explain{}, test.plan); got != test.want {
+			t.Errorf("MissingStatisticsHint(%v rows, factor %v) = %q, want %q", test.plan.PlanRows, test.plan.PlannerRowEstimateFactor, got, test.want)
+		}
+	}
+
+	if got := MissingStatisticsHint(&Explain{EstimateOnly: true}, scan(2550, 40)); got != "" {
+		t.Errorf("MissingStatisticsHint on an estimate-only plan = %q, want none", got)
+	}
+}
+
+func TestDuplicateScanHint(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 100, "Plan Rows": 10, "Actual Total Time": 30, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "t", "Schema": "public", "Total Cost": 40, "Plan Rows": 100, "Actual Total Time": 10, "Actual Rows": 100, "Actual Loops": 1},
+			{"Node Type": "Hash", "Parent Relationship": "Inner", "Total Cost": 40, "Plan Rows": 100, "Actual Total Time": 12, "Actual Rows": 100, "Actual Loops": 1,
+				"Plans": [{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "t", "Schema": "public", "Total Cost": 40, "Plan Rows": 100, "Actual Total Time": 10, "Actual Rows": 100, "Actual Loops": 1}]}]},
+		"Execution Time": 30}]`)
+
+	first, second := &explain.Plan.Plans[0], &explain.Plan.Plans[1].Plans[0]
+
+	if got, want := DuplicateScanHint(explain, first), "public.t is scanned 2 times (#2, #4) taking 20.00 ms in total, consolidating the scans may help"; got != want {
+		t.Errorf("DuplicateScanHint on the first scan = %q, want %q", got, want)
+	}
+
+	if got := DuplicateScanHint(explain, second); got != "" {
+		t.Errorf("DuplicateScanHint on the second scan = %q, want none", got)
+	}
+
+	second.RelationName = "u"
+
+	if got := DuplicateScanHint(explain, first); got != "" {
+		t.Errorf("DuplicateScanHint with distinct relations = %q, want none", got)
+	}
+}
+
+func TestHashAggSpillHint(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want string
+	}{
+		{Plan{NodeType: Aggregate, Strategy: "Hashed", HashAggBatches: 5, DiskUsage: 4096}, "hash aggregate spilled 4.2 MB to disk in 5 batches, consider raising hash_mem_multiplier"},
+		{Plan{NodeType: Aggregate, Strategy: "Hashed", HashAggBatches: 1}, ""},
+		{Plan{NodeType: Sort, DiskUsage: 4096}, ""},
+	}
+
+	for _, test := range tests {
+		if got := HashAggSpillHint(&Explain{}, &test.plan); got != test.want {
+			t.Errorf("HashAggSpillHint(%v, %v kB) = %q, want %q", test.plan.NodeType, test.plan.DiskUsage, got, test.want)
+		}
+	}
+
+	explain := analyzeFile(t, "hashagg.json")
+
+	found := false
+
+	for _, warning := range Warnings(explain) {
+		found = found || warning.Kind == DiskSpill
+	}
+
+	if !found {
+		t.Errorf("hash aggregate spill not reported as %v", DiskSpill)
+	}
+}
+
+func TestSingleCopyHint(t *testing.T) {
+	if got := SingleCopyHint(&Explain{}, &Plan{NodeType: Gather, SingleCopy: true}); !strings.HasPrefix(got, "single copy:") {
+		t.Errorf("SingleCopyHint on a single copy Gather = %q", got)
+	}
+
+	if got := SingleCopyHint(&Explain{}, &Plan{NodeType: Gather, WorkersLaunched: 2}); got != "" {
+		t.Errorf("SingleCopyHint on a parallel Gather = %q, want none", got)
+	}
+
+	explain := analyzeFile(t, "singlecopy.json")
+	found := false
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		found = found || SingleCopyHint(explain, plan) != ""
+	})
+
+	if !found {
+		t.Errorf("no single copy hint in singlecopy.json")
+	}
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "Gather [Single Copy]") {
+		t.Errorf("output does not flag the single copy Gather:\n%v", rendered)
+	}
+}
+
+func TestSkewedAppendHint(t *testing.T) {
+	appendOf := func(rows ...uint64) *Plan {
+		plan := &Plan{NodeType: Append}
+
+		for index, count := range rows {
+			plan.Plans = append(plan.Plans, Plan{ID: index + 2, NodeType: SequenceScan, PlanRows: count})
+		}
+
+		return plan
+	}
+
+	estimated := &Explain{EstimateOnly: true}
+
+	tests := []struct {
+		plan *Plan
+		want string
+	}{
+		{appendOf(10, 10, 900), "#4 accounts for 98% of the rows of its 3 children, the partitioning is not spreading the load"},
+		{appendOf(300, 300, 400), ""},
+		{appendOf(10, 990), ""},
+		{appendOf(0, 0, 0), ""},
+	}
+
+	for _, test := range tests {
+		if got := SkewedAppendHint(estimated, test.plan); got != test.want {
+			t.Errorf("SkewedAppendHint(%d children) = %q, want %q", len(test.plan.Plans), got, test.want)
+		}
+	}
+
+	explain := analyzeFile(t, "skewed.json")
+
+	if got := SkewedAppendHint(explain, &explain.Plan); !strings.Contains(got, "of the time of its 4 children") {
+		t.Errorf("SkewedAppendHint on skewed.json = %q, want it judged on time", got)
+	}
+}
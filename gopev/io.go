@@ -0,0 +1,23 @@
+package gopev
+
+import "math"
+
+// TotalIOTime returns the time, in ms, the plan spent reading and writing
+// blocks, as reported with track_io_timing. Each node's I/O times include
+// its children's, so each node adds only the time beyond theirs, and never
+// less than nothing.
+func TotalIOTime(explain *Explain) (read, write float64) {
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		ownRead, ownWrite := plan.IOReadTime, plan.IOWriteTime
+
+		for _, child := range plan.Plans {
+			ownRead -= child.IOReadTime
+			ownWrite -= child.IOWriteTime
+		}
+
+		read += math.Max(ownRead, 0)
+		write += math.Max(ownWrite, 0)
+	})
+
+	return read, write
+}
@@ -0,0 +1,33 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTotalIOTime(t *testing.T) {
+	explain := &Explain{Plan: Plan{IOReadTime: 10, IOWriteTime: 1, Plans: []Plan{
+		{IOReadTime: 6, Plans: []Plan{{IOReadTime: 4}}},
+		{IOReadTime: 3, IOWriteTime: 2},
+	}}}
+
+	// The root reports less write time than its child, which must not
+	// count against the total.
+	read, write := TotalIOTime(explain)
+
+	if read != 10 || write != 2 {
+		t.Errorf("TotalIOTime = %v, %v, want 10, 2", read, write)
+	}
+}
+
+func TestIOTimeHeader(t *testing.T) {
+	rendered := renderPlain(t, analyzeFile(t, "iotiming.json"), Options{})
+
+	if !strings.Contains(rendered, "○ I/O Time: 9.41 ms (38%)\n") {
+		t.Errorf("output missing the I/O time header:\n%v", rendered)
+	}
+
+	if rendered := renderPlain(t, analyzeOne(t, nestedLoopFunctionScan), Options{}); strings.Contains(rendered, "I/O Time") {
+		t.Errorf("I/O time shown for a plan without track_io_timing:\n%v", rendered)
+	}
+}
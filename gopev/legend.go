@@ -0,0 +1,24 @@
+package gopev
+
+import (
+	"fmt"
+	"io"
+)
+
+func WriteLegend(writer io.Writer) {
+	fmt.Fprintf(writer, "%v Legend:\n", Glyphs.Bullet)
+
+	for _, entry := range [][2]string{
+		{FormatTag("slowest"), "the node with the longest self-time"},
+		{FormatTag("costliest"), "the node with the highest cost of its own"},
+		{FormatTag("largest"), "the node returning the most rows"},
+		{FormatTag("bad estimate"), fmt.Sprintf("the planner underestimated rows by %gx or more, or overestimated them by %gx or more", BadUnderestimateFactor, BadOverestimateFactor)},
+		{FormatTag("slow for cost"), "ran 10x longer than its share of the cost implied"},
+		{WarningFormat("hint"), "an advisory about a likely problem with the node"},
+	} {
+		fmt.Fprintf(writer, "  %v %v\n", entry[0], MutedFormat(entry[1]))
+	}
+
+	fmt.Fprintf(writer, "  %v %v %v %v\n", MutedFormat("durations:"), GoodFormat("under 100 ms"), WarningFormat("under 1 s"), CriticalFormat("1 s or more"))
+	fmt.Fprintf(writer, "  %v %v %v %v\n", MutedFormat("node types, by row estimate:"), GoodFormat("within 10x"), WarningFormat("10x or more"), CriticalFormat("bad estimate"))
+}
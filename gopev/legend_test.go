@@ -0,0 +1,39 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteLegend(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	under, over := BadUnderestimateFactor, BadOverestimateFactor
+	defer func() { BadUnderestimateFactor, BadOverestimateFactor = under, over }()
+
+	BadUnderestimateFactor, BadOverestimateFactor = 50, 500
+
+	var written strings.Builder
+	WriteLegend(&written)
+
+	for _, want := range []string{"○ Legend:\n", "[ slowest ]", "underestimated rows by 50x or more, or overestimated them by 500x or more"} {
+		if !strings.Contains(written.String(), want) {
+			t.Errorf("legend missing %q:\n%v", want, written.String())
+		}
+	}
+}
+
+func TestShowLegend(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	if rendered := renderPlain(t, explain, Options{}); strings.Contains(rendered, "Legend:") {
+		t.Errorf("legend shown without ShowLegend:\n%v", rendered)
+	}
+
+	if rendered := renderPlain(t, explain, Options{ShowLegend: true}); !strings.Contains(rendered, "Legend:") {
+		t.Errorf("legend missing with ShowLegend:\n%v", rendered)
+	}
+}
@@ -0,0 +1,72 @@
+package gopev
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// lineLimiter passes through the first limit lines written to it and counts
+// the rest.
+type lineLimiter struct {
+	writer  io.Writer
+	limit   int
+	lines   int
+	skipped int
+}
+
+func (limiter *lineLimiter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		chunk := p
+		end := bytes.IndexByte(p, '\n')
+
+		if end >= 0 {
+			chunk = p[:end+1]
+		}
+
+		if limiter.lines < limiter.limit {
+			if _, err := limiter.writer.Write(chunk); err != nil {
+				return 0, err
+			}
+		} else if end >= 0 {
+			limiter.skipped++
+		}
+
+		if end >= 0 {
+			limiter.lines++
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+func (limiter *lineLimiter) Close() {
+	if limiter.skipped > 0 {
+		fmt.Fprintf(limiter.writer, "%v\n", MutedFormat(fmt.Sprintf("… output truncated (%v more lines) …", limiter.skipped)))
+	}
+}
+
+// errorWriter remembers the first error returned by writer and fails every
+// write after it, so that rendering can stop early and report the error.
+type errorWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (output *errorWriter) Write(p []byte) (int, error) {
+	if output.err != nil {
+		return 0, output.err
+	}
+
+	written, err := output.writer.Write(p)
+
+	if err != nil {
+		output.err = err
+	}
+
+	return written, err
+}
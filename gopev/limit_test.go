@@ -0,0 +1,98 @@
+package gopev
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLineLimiter(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	var written strings.Builder
+	limiter := &lineLimiter{writer: &written, limit: 2}
+
+	for _, chunk := range []string{"one\ntw", "o\nthree\n", "four\n"} {
+		if n, err := limiter.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %v, %v, want %v, nil", chunk, n, err, len(chunk))
+		}
+	}
+
+	limiter.Close()
+
+	if want := "one\ntwo\n… output truncated (2 more lines) …\n"; written.String() != want {
+		t.Errorf("lineLimiter wrote %q, want %q", written.String(), want)
+	}
+}
+
+func TestMaxLines(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	full := strings.Split(strings.TrimSuffix(renderPlain(t, explain, Options{}), "\n"), "\n")
+	limited := strings.Split(strings.TrimSuffix(renderPlain(t, explain, Options{MaxLines: 5}), "\n"), "\n")
+
+	if len(limited) != 6 {
+		t.Fatalf("MaxLines 5 rendered %d lines, want 5 and a note:\n%v", len(limited), strings.Join(limited, "\n"))
+	}
+
+	for index := 0; index < 5; index++ {
+		if limited[index] != full[index] {
+			t.Errorf("line %d = %q, want %q", index, limited[index], full[index])
+		}
+	}
+
+	if want := "… output truncated (" + fmt.Sprint(len(full)-5) + " more lines) …"; limited[5] != want {
+		t.Errorf("last line = %q, want %q", limited[5], want)
+	}
+}
+
+// failingWriter accepts limit writes and fails every one after them.
+type failingWriter struct {
+	limit  int
+	writes int
+}
+
+func (writer *failingWriter) Write(p []byte) (int, error) {
+	writer.writes++
+
+	if writer.writes > writer.limit {
+		return 0, errors.New("broken pipe")
+	}
+
+	return len(p), nil
+}
+
+func TestWriteExplainStopsOnWriteError(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	for _, options := range []Options{{}, {MaxLines: 4}} {
+		writer := &failingWriter{limit: 3}
+
+		if err := WriteExplain(writer, explain, options); err == nil || err.Error() != "broken pipe" {
+			t.Errorf("WriteExplain(%+v) = %v, want broken pipe", options, err)
+		}
+
+		if writer.writes != 4 {
+			t.Errorf("WriteExplain(%+v) kept writing after the error: %d writes", options, writer.writes)
+		}
+	}
+
+	if err := Render(&failingWriter{limit: 0}, explain); err == nil {
+		t.Errorf("Render ignored the write error")
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	output := &errorWriter{writer: &failingWriter{limit: 1}}
+
+	output.Write([]byte("one\n"))
+	output.Write([]byte("two\n"))
+
+	if n, err := output.Write([]byte("three\n")); n != 0 || err == nil || output.err != err {
+		t.Errorf("Write after an error = %v, %v, want 0 and the first error", n, err)
+	}
+}
@@ -0,0 +1,99 @@
+package gopev
+
+type Severity int
+
+const (
+	SeverityGood Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+// PlanLine is a single line of the rendered tree. NodeIndex is the ID of the
+// node the line belongs to, Depth its depth in the tree, and Header is set on
+// the line naming the node.
+//
+// RenderLines marks the header of every node with children as Collapsible;
+// the lines of its descendants are then ChildStart up to, but not including,
+// ChildEnd, so a viewer can fold them away.
+type PlanLine struct {
+	Depth       int
+	NodeIndex   int
+	Text        string
+	Severity    Severity
+	Header      bool
+	Collapsible bool
+	ChildStart  int
+	ChildEnd    int
+}
+
+// RenderLines renders a processed Explain as a list of lines rather than
+// writing it out, for callers that lay the tree out themselves.
+func RenderLines(explain *Explain) []PlanLine {
+	var lines []PlanLine
+
+	renderPlan(explain, &explain.Plan, Options{}, "", 0, len(explain.Plan.Plans) == 1, func(line PlanLine) bool {
+		lines = append(lines, line)
+		return true
+	})
+
+	markCollapsible(explain, lines)
+
+	return lines
+}
+
+func markCollapsible(explain *Explain, lines []PlanLine) {
+	ancestors := map[int][]int{}
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		for _, parent := range path {
+			ancestors[plan.ID] = append(ancestors[plan.ID], parent.ID)
+		}
+	})
+
+	headers, last, end := map[int]int{}, map[int]int{}, map[int]int{}
+
+	for index, line := range lines {
+		if line.Header {
+			headers[line.NodeIndex] = index
+		}
+		last[line.NodeIndex] = index
+
+		for _, ancestor := range ancestors[line.NodeIndex] {
+			end[ancestor] = index + 1
+		}
+	}
+
+	for id, childEnd := range end {
+		index, ok := headers[id]
+
+		if !ok {
+			continue
+		}
+
+		lines[index].Collapsible = true
+		lines[index].ChildStart = last[id] + 1
+		lines[index].ChildEnd = childEnd
+	}
+}
+
+// NodeSeverity rates a node by its self-time and row estimate, raising it to
+// at least a warning when any hint applies.
+func NodeSeverity(explain *Explain, plan *Plan) Severity {
+	return nodeSeverity(explain, plan, Options{})
+}
+
+func nodeSeverity(explain *Explain, plan *Plan, options Options) Severity {
+	severity := DurationSeverity(plan.ActualDuration)
+
+	if IsBadEstimate(plan) {
+		severity = SeverityCritical
+	} else if plan.PlannerRowEstimateFactor >= 10 && severity < SeverityWarning {
+		severity = SeverityWarning
+	}
+
+	if len(planHints(explain, plan, options)) > 0 && severity < SeverityWarning {
+		severity = SeverityWarning
+	}
+
+	return severity
+}
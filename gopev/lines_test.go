@@ -0,0 +1,94 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderLines(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	lines := RenderLines(explain)
+
+	if len(lines) == 0 {
+		t.Fatal("RenderLines returned no lines")
+	}
+
+	depths := map[int]int{1: 0, 2: 1, 3: 1}
+	var order []int
+
+	for _, line := range lines {
+		if strings.Contains(line.Text, "\n") {
+			t.Errorf("line of node #%d contains a newline: %q", line.NodeIndex, line.Text)
+		}
+
+		if depth, ok := depths[line.NodeIndex]; !ok || line.Depth != depth {
+			t.Errorf("line %q of node #%d at depth %d, want %d", line.Text, line.NodeIndex, line.Depth, depth)
+		}
+
+		if len(order) == 0 || order[len(order)-1] != line.NodeIndex {
+			order = append(order, line.NodeIndex)
+		}
+	}
+
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("lines grouped by node %v, want [1 2 3]", order)
+	}
+
+	if !strings.Contains(lines[1].Text, "#1 Nested Loop") {
+		t.Errorf("second line = %q, want the Nested Loop", lines[1].Text)
+	}
+}
+
+func TestRenderLinesCollapsible(t *testing.T) {
+	explain := Explain{Plan: Plan{NodeType: NestedLoop, ActualLoops: 1, Plans: []Plan{
+		{NodeType: Hash, ActualLoops: 1, Plans: []Plan{{NodeType: SequenceScan, ActualLoops: 1}}},
+		{NodeType: IndexScan, ActualLoops: 1},
+	}}}
+
+	ProcessExplain(&explain)
+
+	lines := RenderLines(&explain)
+	headers := map[int]int{}
+
+	for index, line := range lines {
+		if line.Header {
+			if _, ok := headers[line.NodeIndex]; ok {
+				t.Errorf("node #%d has more than one header", line.NodeIndex)
+			}
+			headers[line.NodeIndex] = index
+		}
+	}
+
+	if len(headers) != 4 {
+		t.Fatalf("headers for %d nodes, want 4", len(headers))
+	}
+
+	for id, index := range headers {
+		line := lines[index]
+
+		if line.Collapsible != (id == 1 || id == 2) {
+			t.Errorf("node #%d Collapsible = %v", id, line.Collapsible)
+		}
+
+		if !line.Collapsible {
+			continue
+		}
+
+		for child := line.ChildStart; child < line.ChildEnd; child++ {
+			if lines[child].NodeIndex == id {
+				t.Errorf("node #%d folds its own line %q", id, lines[child].Text)
+			}
+		}
+	}
+
+	root, hash := lines[headers[1]], lines[headers[2]]
+
+	if root.ChildStart > headers[2] || root.ChildEnd != len(lines) {
+		t.Errorf("root folds lines %d..%d, want from before #2 at %d to the end %d", root.ChildStart, root.ChildEnd, headers[2], len(lines))
+	}
+
+	if hash.ChildStart > headers[3] || hash.ChildEnd > headers[4] {
+		t.Errorf("Hash folds lines %d..%d, want #3 at %d but not #4 at %d", hash.ChildStart, hash.ChildEnd, headers[3], headers[4])
+	}
+}
@@ -0,0 +1,58 @@
+package gopev
+
+import (
+	"fmt"
+	"io"
+)
+
+// Lint writes the warnings for every plan in buffer as a flat list, one per
+// line, without drawing the tree, and returns how many it found so callers
+// such as CI jobs can fail on them.
+func Lint(writer io.Writer, buffer []byte) (problemCount int, err error) {
+	return LintWithOptions(writer, buffer, Options{})
+}
+
+// LintWithOptions is Lint with the opt-in hints and MaxDepth of options.
+func LintWithOptions(writer io.Writer, buffer []byte, options Options) (problemCount int, err error) {
+	explains, err := analyze(buffer, options.MaxDepth)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for index, _ := range explains {
+		explain := &explains[index]
+
+		for _, warning := range collectWarnings(explain, options) {
+			plan := NodeByID(explain, warning.NodeIndex)
+
+			location := fmt.Sprintf("#%d %v", plan.ID, plan.NodeType)
+			if plan.Schema != "" {
+				location += fmt.Sprintf(" on %v.%v", plan.Schema, plan.RelationName)
+			} else if plan.RelationName != "" {
+				location += fmt.Sprintf(" on %v", plan.RelationName)
+			}
+
+			_, err = fmt.Fprintf(writer, "%v: %v %v: %v\n", location, SeverityFormat(warning.Severity)(severityName(warning.Severity)), warning.Kind, warning.Message)
+
+			if err != nil {
+				return problemCount, err
+			}
+
+			problemCount++
+		}
+	}
+
+	return problemCount, nil
+}
+
+func severityName(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "good"
+	}
+}
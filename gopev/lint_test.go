@@ -0,0 +1,67 @@
+package gopev
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	var written strings.Builder
+
+	count, err := Lint(&written, []byte(`[{"Plan": {"Node Type": "Sort", "Sort Key": ["t.a"], "Sort Method": "external merge", "Sort Space Used": 20000, "Sort Space Type": "Disk", "Total Cost": 100, "Plan Rows": 10, "Actual Total Time": 60, "Actual Rows": 100000, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(b = 1)", "Rows Removed by Filter": 900000, "Total Cost": 90, "Plan Rows": 10, "Actual Total Time": 30, "Actual Rows": 100000, "Actual Loops": 1}]},
+		"Execution Time": 60}]`))
+
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(written.String(), "\n"), "\n")
+
+	if count == 0 || count != len(lines) {
+		t.Errorf("Lint returned %d problems and wrote %d lines:\n%v", count, len(lines), written.String())
+	}
+
+	for _, want := range []string{"#1 Sort: ", "#2 Seq Scan on public.t: "} {
+		if !strings.Contains(written.String(), "\n"+want) && !strings.HasPrefix(written.String(), want) {
+			t.Errorf("Lint output has no line for %q:\n%v", want, written.String())
+		}
+	}
+
+	if strings.Contains(written.String(), "─⌠") {
+		t.Errorf("Lint drew the tree:\n%v", written.String())
+	}
+}
+
+func TestLintCleanPlan(t *testing.T) {
+	var written strings.Builder
+
+	count, err := Lint(&written, []byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}]`))
+
+	if err != nil || count != 0 || written.Len() != 0 {
+		t.Errorf("Lint of a clean plan = %d, %v, %q, want no problems", count, err, written.String())
+	}
+}
+
+func TestLintWithOptions(t *testing.T) {
+	buffer, err := os.ReadFile("testdata/serial.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written strings.Builder
+
+	plain, _ := Lint(&written, buffer)
+	serial, _ := LintWithOptions(&written, buffer, Options{SerialPlanHint: true})
+
+	if serial != plain+1 {
+		t.Errorf("LintWithOptions with SerialPlanHint found %d problems, want %d", serial, plain+1)
+	}
+}
@@ -0,0 +1,48 @@
+package gopev
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ExtractPlanFromLog finds the JSON plan written by auto_explain inside a
+// server log line. auto_explain logs a single object, so it is wrapped in an
+// array to match the EXPLAIN output Visualize expects.
+func ExtractPlanFromLog(line string) ([]byte, error) {
+	for index, char := range line {
+		if char != '{' && char != '[' {
+			continue
+		}
+
+		var value json.RawMessage
+
+		decoder := json.NewDecoder(strings.NewReader(line[index:]))
+
+		if decoder.Decode(&value) != nil {
+			continue
+		}
+
+		if char == '[' {
+			var explain []Explain
+
+			if json.Unmarshal(value, &explain) == nil && len(explain) > 0 {
+				return value, nil
+			}
+
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+
+		if json.Unmarshal(value, &fields) != nil {
+			continue
+		}
+
+		if _, ok := fields["Plan"]; ok {
+			return append(append([]byte("["), value...), ']'), nil
+		}
+	}
+
+	return nil, errors.New("no JSON plan found in log line")
+}
@@ -0,0 +1,45 @@
+package gopev
+
+import (
+	"testing"
+)
+
+func TestExtractPlanFromLog(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{
+			`2024-01-02 10:00:00 UTC [42] LOG:  duration: 1.234 ms  plan: {"Query Text": "select 1", "Plan": {"Node Type": "Result"}}`,
+			`[{"Query Text": "select 1", "Plan": {"Node Type": "Result"}}]`,
+		},
+		{
+			`LOG:  plan: [{"Plan": {"Node Type": "Result"}}] trailing text`,
+			`[{"Plan": {"Node Type": "Result"}}]`,
+		},
+		{
+			`LOG:  context {not json} then {"Plan": {"Node Type": "Result"}}`,
+			`[{"Plan": {"Node Type": "Result"}}]`,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := ExtractPlanFromLog(test.line)
+
+		if err != nil || string(got) != test.want {
+			t.Errorf("ExtractPlanFromLog(%q) = %q, %v, want %q", test.line, got, err, test.want)
+		}
+	}
+}
+
+func TestExtractPlanFromLogWithoutPlan(t *testing.T) {
+	for _, line := range []string{
+		"LOG:  checkpoint complete",
+		`LOG:  parameters: {"a": 1}`,
+		`LOG:  [1, 2, 3]`,
+	} {
+		if got, err := ExtractPlanFromLog(line); err == nil {
+			t.Errorf("ExtractPlanFromLog(%q) = %q, want an error", line, got)
+		}
+	}
+}
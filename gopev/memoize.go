@@ -0,0 +1,24 @@
+package gopev
+
+// MemoizeHitRatio and MemoizeEvictionRatio are the share of lookups a
+// Memoize node should answer from its cache, and the share of misses that
+// may evict an entry, before MemoizeHint warns about it.
+var MemoizeHitRatio float64 = 0.5
+
+var MemoizeEvictionRatio float64 = 0.1
+
+// MemoizeLookups is the number of lookups below which MemoizeHint stays
+// quiet, as a few misses are expected while the cache fills.
+var MemoizeLookups uint64 = 100
+
+// MemoizeHitRate returns the share of a Memoize node's lookups answered from
+// its cache, or 0 if it made none.
+func MemoizeHitRate(plan *Plan) float64 {
+	lookups := plan.CacheHits + plan.CacheMisses
+
+	if lookups == 0 {
+		return 0
+	}
+
+	return float64(plan.CacheHits) / float64(lookups)
+}
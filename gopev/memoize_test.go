@@ -0,0 +1,34 @@
+package gopev
+
+import (
+	"testing"
+)
+
+func TestMemoizeHitRate(t *testing.T) {
+	if got := MemoizeHitRate(&Plan{CacheHits: 75, CacheMisses: 25}); got != 0.75 {
+		t.Errorf("MemoizeHitRate = %v, want 0.75", got)
+	}
+
+	if got := MemoizeHitRate(&Plan{}); got != 0 {
+		t.Errorf("MemoizeHitRate without lookups = %v, want 0", got)
+	}
+}
+
+func TestMemoizeHint(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want string
+	}{
+		{Plan{NodeType: Memoize, CacheHits: 100, CacheMisses: 900}, "cache answered only 10% of 1,000 lookups, the join may be cheaper without it (check the n_distinct estimate of the cache key)"},
+		{Plan{NodeType: Memoize, CacheHits: 800, CacheMisses: 200, CacheEvictions: 50}, "cache evicted 50 entries as it ran out of memory, consider raising work_mem or hash_mem_multiplier"},
+		{Plan{NodeType: Memoize, CacheHits: 800, CacheMisses: 200, CacheEvictions: 5}, ""},
+		{Plan{NodeType: Memoize, CacheHits: 10, CacheMisses: 80}, ""},
+		{Plan{NodeType: Materialize, CacheHits: 100, CacheMisses: 900}, ""},
+	}
+
+	for _, test := range tests {
+		if got := MemoizeHint(&Explain{}, &test.plan); got != test.want {
+			t.Errorf("MemoizeHint(%v hits, %v misses, %v evictions) = %q, want %q", test.plan.CacheHits, test.plan.CacheMisses, test.plan.CacheEvictions, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gopev
+
+// UsesWorkMem reports whether a node keeps its working set in memory bounded
+// by work_mem.
+func UsesWorkMem(plan *Plan) bool {
+	switch plan.NodeType {
+	case Sort, IncrementalSort, Hash:
+		return true
+	case Aggregate:
+		return plan.Strategy == "Hashed" || plan.Strategy == "Mixed"
+	}
+
+	return false
+}
+
+// DefaultHashMemMultiplier is PostgreSQL's default hash_mem_multiplier, used
+// when Options.HashMemMultiplier is not set.
+const DefaultHashMemMultiplier = 2.0
+
+// UsesHashMem reports whether a node's memory is bounded by work_mem times
+// hash_mem_multiplier rather than by work_mem alone.
+func UsesHashMem(plan *Plan) bool {
+	switch plan.NodeType {
+	case Hash:
+		return true
+	case Aggregate:
+		return plan.Strategy == "Hashed" || plan.Strategy == "Mixed"
+	}
+
+	return false
+}
+
+// MemoryLimitKB is the memory, in kB, a node may use before spilling to
+// disk, given work_mem and hash_mem_multiplier. A multiplier of 0 means
+// DefaultHashMemMultiplier.
+func MemoryLimitKB(plan *Plan, workMemKB uint64, hashMemMultiplier float64) uint64 {
+	if !UsesHashMem(plan) {
+		return workMemKB
+	}
+
+	if hashMemMultiplier == 0 {
+		hashMemMultiplier = DefaultHashMemMultiplier
+	}
+
+	return uint64(float64(workMemKB) * hashMemMultiplier)
+}
+
+// EstimatedMemoryKB estimates the memory a node needed, in kB. It uses the
+// sort space or peak memory the node reports when that reflects the whole
+// working set, and otherwise its rows times their width.
+func EstimatedMemoryKB(plan *Plan) uint64 {
+	if plan.SortSpaceUsed > 0 {
+		return plan.SortSpaceUsed
+	}
+
+	if plan.PeakMemoryUsage > 0 && plan.HashBatches <= 1 && plan.HashAggBatches <= 1 {
+		return plan.PeakMemoryUsage
+	}
+
+	rows := plan.ActualRows
+	if plan.ActualLoops == 0 {
+		rows = plan.PlanRows
+	}
+
+	return rows * plan.PlanWidth / 1024
+}
@@ -0,0 +1,57 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryLimitKB(t *testing.T) {
+	tests := []struct {
+		plan       Plan
+		multiplier float64
+		want       uint64
+	}{
+		{Plan{NodeType: Sort}, 0, 4096},
+		{Plan{NodeType: Sort}, 3, 4096},
+		{Plan{NodeType: Hash}, 0, 8192},
+		{Plan{NodeType: Hash}, 1.5, 6144},
+		{Plan{NodeType: Aggregate, Strategy: "Hashed"}, 0, 8192},
+		{Plan{NodeType: Aggregate, Strategy: "Sorted"}, 0, 4096},
+	}
+
+	for _, test := range tests {
+		if got := MemoryLimitKB(&test.plan, 4096, test.multiplier); got != test.want {
+			t.Errorf("MemoryLimitKB(%v %v, ×%v) = %v, want %v", test.plan.NodeType, test.plan.Strategy, test.multiplier, got, test.want)
+		}
+	}
+}
+
+func TestEstimatedMemoryKB(t *testing.T) {
+	tests := []struct {
+		plan Plan
+		want uint64
+	}{
+		{Plan{SortSpaceUsed: 300, ActualRows: 10, ActualLoops: 1, PlanWidth: 1024}, 300},
+		{Plan{PeakMemoryUsage: 500, HashBatches: 1, ActualRows: 10, ActualLoops: 1, PlanWidth: 1024}, 500},
+		{Plan{PeakMemoryUsage: 500, HashBatches: 4, ActualRows: 4096, ActualLoops: 1, PlanWidth: 512}, 2048},
+		{Plan{PlanRows: 2048, PlanWidth: 1024}, 2048},
+	}
+
+	for _, test := range tests {
+		if got := EstimatedMemoryKB(&test.plan); got != test.want {
+			t.Errorf("EstimatedMemoryKB(%+v) = %v, want %v", test.plan, got, test.want)
+		}
+	}
+}
+
+func TestWorkMemLineUsesHashMemMultiplier(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Hash", "Total Cost": 10, "Plan Rows": 100, "Plan Width": 1024, "Actual Total Time": 5, "Actual Rows": 6000, "Actual Loops": 1, "Hash Batches": 1, "Peak Memory Usage": 6000}, "Execution Time": 5}]`)
+
+	if rendered := renderPlain(t, explain, Options{WorkMemKB: 4096}); strings.Contains(rendered, "exceeds") {
+		t.Errorf("6000 kB hash flagged against 2 × 4096 kB:\n%v", rendered)
+	}
+
+	if rendered := renderPlain(t, explain, Options{WorkMemKB: 4096, HashMemMultiplier: 1}); !strings.Contains(rendered, "exceeds work_mem × hash_mem_multiplier of 4.2 MB") {
+		t.Errorf("6000 kB hash not flagged against 1 × 4096 kB:\n%v", rendered)
+	}
+}
@@ -0,0 +1,90 @@
+package gopev
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// NodeMetrics is the record VisualizeNDJSON writes for each node.
+type NodeMetrics struct {
+	Plan     int           `json:"plan"`
+	ID       int           `json:"id"`
+	Depth    int           `json:"depth"`
+	Type     NodeType      `json:"type"`
+	Relation string        `json:"relation,omitempty"`
+	SelfMs   float64       `json:"self_ms"`
+	Rows     uint64        `json:"rows"`
+	Loops    uint64        `json:"loops"`
+	Cost     float64       `json:"cost"`
+	Buffers  BufferMetrics `json:"buffers"`
+}
+
+// BufferMetrics are a node's block counts, including its children's as
+// EXPLAIN reports them.
+type BufferMetrics struct {
+	SharedHit     uint64 `json:"shared_hit"`
+	SharedRead    uint64 `json:"shared_read"`
+	SharedDirtied uint64 `json:"shared_dirtied"`
+	SharedWritten uint64 `json:"shared_written"`
+	TempRead      uint64 `json:"temp_read"`
+	TempWritten   uint64 `json:"temp_written"`
+}
+
+// VisualizeNDJSON writes one JSON object per node of each plan in buffer,
+// one per line, for shipping plan metrics to other tools. Plan is the index
+// of the plan in buffer; for estimate-only plans self_ms is 0 and rows are
+// the planner's estimate.
+func VisualizeNDJSON(writer io.Writer, buffer []byte) error {
+	explains, err := Analyze(buffer)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(writer)
+
+	for index, _ := range explains {
+		explain := &explains[index]
+
+		CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+			if err != nil {
+				return
+			}
+
+			metrics := NodeMetrics{
+				Plan:   index,
+				ID:     plan.ID,
+				Depth:  len(path),
+				Type:   plan.NodeType,
+				SelfMs: plan.ActualDuration,
+				Rows:   plan.ActualRows,
+				Loops:  plan.ActualLoops,
+				Cost:   plan.ActualCost,
+				Buffers: BufferMetrics{
+					SharedHit:     plan.SharedHitBlocks,
+					SharedRead:    plan.SharedReadBlocks,
+					SharedDirtied: plan.SharedDirtiedBlocks,
+					SharedWritten: plan.SharedWrittenBlocks,
+					TempRead:      plan.TempReadBlocks,
+					TempWritten:   plan.TempWrittenBlocks,
+				},
+			}
+
+			if plan.RelationName != "" {
+				metrics.Relation = plan.Schema + "." + plan.RelationName
+			}
+
+			if explain.EstimateOnly {
+				metrics.Rows = plan.PlanRows
+			}
+
+			err = encoder.Encode(metrics)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package gopev
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeNDJSON(t *testing.T, buffer string) []NodeMetrics {
+	t.Helper()
+
+	var written strings.Builder
+
+	if err := VisualizeNDJSON(&written, []byte(buffer)); err != nil {
+		t.Fatalf("VisualizeNDJSON: %v", err)
+	}
+
+	var records []NodeMetrics
+
+	scanner := bufio.NewScanner(strings.NewReader(written.String()))
+
+	for scanner.Scan() {
+		var record NodeMetrics
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestVisualizeNDJSON(t *testing.T) {
+	records := decodeNDJSON(t, nestedLoopFunctionScan)
+
+	if len(records) != 3 {
+		t.Fatalf("VisualizeNDJSON wrote %d records, want 3", len(records))
+	}
+
+	scan := records[1]
+
+	if scan.ID != 2 || scan.Depth != 1 || scan.Type != SequenceScan || scan.Relation != "public.a" || scan.SelfMs != 20 || scan.Rows != 100 || scan.Loops != 1 || scan.Cost != 900 {
+		t.Errorf("Seq Scan record = %+v", scan)
+	}
+
+	if function := records[2]; function.Loops != 100 || function.Relation != "" {
+		t.Errorf("Function Scan record = %+v", function)
+	}
+}
+
+func TestVisualizeNDJSONEstimateOnly(t *testing.T) {
+	plan := `{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 250}}`
+	records := decodeNDJSON(t, "["+plan+","+plan+"]")
+
+	if len(records) != 2 || records[1].Plan != 1 {
+		t.Fatalf("records = %+v, want one per plan", records)
+	}
+
+	if records[0].SelfMs != 0 || records[0].Rows != 250 {
+		t.Errorf("estimate-only record = %+v, want no time and the planner rows", records[0])
+	}
+}
@@ -0,0 +1,81 @@
+package gopev
+
+import (
+	"github.com/dustin/go-humanize"
+	"math"
+	"strings"
+)
+
+// The number formatters used for rows, costs and sizes. Applying a
+// NumberFormat replaces them.
+var FormatInteger = humanize.Comma
+var FormatFloat = humanize.Commaf
+var FormatBytes = humanize.Bytes
+
+var costSuffixes = []string{"B", "T", "Q"}
+
+// FormatCost prints costs in full below a billion and with a suffix above
+// that, where a long run of grouped digits stops being readable. Costs are
+// rounded to the 2 decimals EXPLAIN prints, so differences between them,
+// such as a run cost, do not show float noise.
+func FormatCost(value float64) string {
+	if math.IsInf(value, 0) {
+		return FormatFloat(value)
+	}
+
+	if math.Abs(value) < 1e9 {
+		return FormatFloat(math.Round(value*100) / 100)
+	}
+
+	scaled, suffix := value/1e9, 0
+
+	for math.Abs(scaled) >= 1000 && suffix < len(costSuffixes)-1 {
+		scaled /= 1000
+		suffix++
+	}
+
+	return FormatFloat(math.Round(scaled*100)/100) + costSuffixes[suffix]
+}
+
+// A NumberFormat localizes the grouping and decimal separators, e.g.
+// NumberFormat{Thousands: ".", Decimal: ","} renders 1.234.567,5.
+type NumberFormat struct {
+	Thousands string
+	Decimal   string
+}
+
+var DefaultNumberFormat = NumberFormat{Thousands: ",", Decimal: "."}
+
+// Apply makes format the package's number formatters. It waits for a
+// WriteExplain in progress to put its own formatters back first.
+func (format *NumberFormat) Apply() {
+	renderLock.Lock()
+	defer renderLock.Unlock()
+
+	format.apply()
+}
+
+func (format *NumberFormat) apply() {
+	separators := *format
+
+	if separators == DefaultNumberFormat {
+		FormatInteger = humanize.Comma
+		FormatFloat = humanize.Commaf
+		FormatBytes = humanize.Bytes
+		return
+	}
+
+	FormatInteger = func(value int64) string {
+		return separators.localize(humanize.Comma(value))
+	}
+	FormatFloat = func(value float64) string {
+		return separators.localize(humanize.Commaf(value))
+	}
+	FormatBytes = func(value uint64) string {
+		return separators.localize(humanize.Bytes(value))
+	}
+}
+
+func (format *NumberFormat) localize(value string) string {
+	return strings.NewReplacer(",", format.Thousands, ".", format.Decimal).Replace(value)
+}
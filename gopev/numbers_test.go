@@ -0,0 +1,89 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatCost(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{12.5, "12.5"},
+		{1310.84 - 0.0000000000001, "1,310.84"},
+		{1010.1 - 10, "1,000.1"},
+		{999999999, "999,999,999"},
+		{1.5e9, "1.5B"},
+		{2.25e12, "2.25T"},
+		{7e15, "7Q"},
+		{7e18, "7,000Q"},
+	}
+
+	for _, test := range tests {
+		if got := FormatCost(test.value); got != test.want {
+			t.Errorf("FormatCost(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestNumberFormatApply(t *testing.T) {
+	integer, float, bytes := FormatInteger, FormatFloat, FormatBytes
+	defer func() {
+		FormatInteger, FormatFloat, FormatBytes = integer, float, bytes
+	}()
+
+	(&NumberFormat{Thousands: ".", Decimal: ","}).Apply()
+
+	if got := FormatInteger(1234567); got != "1.234.567" {
+		t.Errorf("FormatInteger = %q, want 1.234.567", got)
+	}
+
+	if got := FormatFloat(1234.5); got != "1.234,5" {
+		t.Errorf("FormatFloat = %q, want 1.234,5", got)
+	}
+
+	DefaultNumberFormat.Apply()
+
+	if got := FormatFloat(1234.5); got != "1,234.5" {
+		t.Errorf("FormatFloat = %q, want 1,234.5", got)
+	}
+}
+
+func TestNumbersOption(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 1234.5, "Plan Rows": 25000, "Actual Total Time": 1, "Actual Rows": 25000, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	rendered := renderPlain(t, explain, Options{Numbers: &NumberFormat{Thousands: " ", Decimal: ","}})
+
+	for _, want := range []string{"Total Cost: 1 234,5", "Rows: 25 000"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+
+	if got := FormatInteger(25000); got != "25,000" {
+		t.Errorf("FormatInteger after WriteExplain = %q, want the default 25,000", got)
+	}
+}
+
+func TestNumberFormatApplyDuringRender(t *testing.T) {
+	defer DefaultNumberFormat.Apply()
+
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	done := make(chan string)
+
+	go func() {
+		var rendered strings.Builder
+		WriteExplain(&rendered, explain, Options{Theme: &NoColorTheme, Numbers: &NumberFormat{Thousands: " ", Decimal: ","}})
+		done <- rendered.String()
+	}()
+
+	for index := 0; index < 100; index++ {
+		(&NumberFormat{Thousands: ".", Decimal: ","}).Apply()
+	}
+
+	if rendered := <-done; !strings.Contains(rendered, "Total Cost: 1 000") {
+		t.Errorf("render with its own NumberFormat picked up another from an Apply:\n%v", rendered)
+	}
+}
@@ -0,0 +1,100 @@
+package gopev
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var qualifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$]*\.`)
+var digitsPattern = regexp.MustCompile(`[0-9]+`)
+
+// SiblingShape describes a subtree with the partition-specific parts (table
+// qualifiers, numbered index names and literals) removed, so that scans of
+// different partitions of the same table compare equal.
+func SiblingShape(plan *Plan) string {
+	shape := []string{
+		string(plan.NodeType),
+		digitsPattern.ReplaceAllString(plan.IndexName, "#"),
+	}
+
+	for _, condition := range []string{plan.IndexCondition, plan.Filter, plan.HashCondition, plan.MergeCondition, plan.JoinFilter} {
+		shape = append(shape, qualifierPattern.ReplaceAllString(RedactLiterals(condition), ""))
+	}
+
+	for index, _ := range plan.Plans {
+		shape = append(shape, "("+SiblingShape(&plan.Plans[index])+")")
+	}
+
+	return strings.Join(shape, "|")
+}
+
+// GroupSiblings groups plans by SiblingShape, in order of first appearance.
+func GroupSiblings(plans []Plan) [][]*Plan {
+	var groups [][]*Plan
+
+	positions := map[string]int{}
+
+	for index, _ := range plans {
+		shape := SiblingShape(&plans[index])
+
+		position, ok := positions[shape]
+
+		if !ok {
+			position = len(groups)
+			positions[shape] = position
+			groups = append(groups, nil)
+		}
+
+		groups[position] = append(groups[position], &plans[index])
+	}
+
+	return groups
+}
+
+func renderCollapsed(explain *Explain, group []*Plan, options Options, prefix string, depth int, lastChild bool, emit func(line PlanLine) bool) bool {
+	var duration float64
+	var rows uint64
+
+	for _, plan := range group {
+		duration += plan.ActualDuration
+		rows += plan.ActualRows
+
+		CollectPlans(plan, func(child *Plan, path []*Plan) {
+			if child != plan {
+				duration += child.ActualDuration
+			}
+		})
+	}
+
+	joint := Glyphs.Branch
+	if lastChild {
+		joint = Glyphs.LastBranch
+	}
+
+	share := 0.0
+	if explain.ExecutionTime > 0 {
+		share = duration / explain.ExecutionTime
+	}
+
+	first := group[0]
+
+	summary := fmt.Sprintf("%v %v %v %v %v (%.0f%%), %v rows", MutedFormat(fmt.Sprintf("#%d", first.ID)), BoldFormat(fmt.Sprintf("×%d", len(group))), EstimateFormat(first)(first.NodeType), MutedFormat("partition scans"), DurationToString(duration), share*100, FormatInteger(int64(rows)))
+
+	lines := []string{
+		PrefixFormat(prefix + Glyphs.Vertical),
+		PrefixFormat(prefix) + PrefixFormat(nodeConnector(joint, indentWidth(options))) + " " + summary,
+	}
+
+	if options.Style == StyleIndent {
+		lines = []string{PrefixFormat(prefix) + summary}
+	}
+
+	for index, text := range lines {
+		if !emit(PlanLine{Depth: depth, NodeIndex: first.ID, Text: text, Severity: nodeSeverity(explain, first, options), Header: index == len(lines)-1}) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,58 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSiblingShape(t *testing.T) {
+	scan := func(relation, index, condition string) Plan {
+		return Plan{NodeType: IndexScan, RelationName: relation, IndexName: index, IndexCondition: condition}
+	}
+
+	first := scan("events_2024", "events_2024_created_idx", "(events_2024.created > '2024-01-01'::date)")
+	second := scan("events_2025", "events_2025_created_idx", "(events_2025.created > '2025-01-01'::date)")
+	other := scan("events_2025", "events_2025_created_idx", "(events_2025.user_id = 42)")
+
+	if SiblingShape(&first) != SiblingShape(&second) {
+		t.Errorf("partition scans differ in shape:\n%v\n%v", SiblingShape(&first), SiblingShape(&second))
+	}
+
+	if SiblingShape(&first) == SiblingShape(&other) {
+		t.Errorf("scans with different conditions share the shape %v", SiblingShape(&first))
+	}
+}
+
+func TestGroupSiblings(t *testing.T) {
+	plans := []Plan{
+		{NodeType: SequenceScan, RelationName: "t_1"},
+		{NodeType: IndexScan, RelationName: "t_2", IndexName: "t_2_pkey"},
+		{NodeType: SequenceScan, RelationName: "t_3"},
+	}
+
+	groups := GroupSiblings(plans)
+
+	if len(groups) != 2 || len(groups[0]) != 2 || len(groups[1]) != 1 {
+		t.Fatalf("GroupSiblings = %v groups, want [2 1]", len(groups))
+	}
+
+	if groups[0][0] != &plans[0] || groups[0][1] != &plans[2] || groups[1][0] != &plans[1] {
+		t.Errorf("GroupSiblings did not keep the order of first appearance")
+	}
+}
+
+func TestCollapsePartitions(t *testing.T) {
+	explain := analyzeFile(t, "partitioned.json")
+
+	rendered := renderPlain(t, explain, Options{})
+
+	if strings.Count(rendered, "Seq Scan [") != 4 {
+		t.Errorf("partition scans collapsed without CollapsePartitions:\n%v", rendered)
+	}
+
+	rendered = renderPlain(t, explain, Options{CollapsePartitions: true})
+
+	if !strings.Contains(rendered, "#2 ×4 Seq Scan partition scans") || strings.Contains(rendered, "#3") {
+		t.Errorf("output did not collapse the partition scans:\n%v", rendered)
+	}
+}
@@ -0,0 +1,43 @@
+package gopev
+
+import (
+	"bytes"
+	"database/sql"
+)
+
+// ExplainQuery runs EXPLAIN on query and returns the JSON plan, ready for
+// Visualize. With analyze the query is executed, so its actual times, rows
+// and buffer counts are included; a data-modifying query takes effect.
+func ExplainQuery(db *sql.DB, query string, analyze bool) ([]byte, error) {
+	options := "FORMAT JSON"
+
+	if analyze {
+		options = "ANALYZE, BUFFERS, FORMAT JSON"
+	}
+
+	rows, err := db.Query("EXPLAIN (" + options + ") " + query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var buffer bytes.Buffer
+
+	for rows.Next() {
+		var line []byte
+
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+
+		buffer.Write(line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
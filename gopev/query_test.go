@@ -0,0 +1,95 @@
+package gopev
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// explainDriver answers every query with the lines in plan, and records the
+// last query it was given.
+type explainDriver struct {
+	plan  []string
+	query string
+}
+
+func (d *explainDriver) Open(name string) (driver.Conn, error) { return &explainConn{d}, nil }
+
+// explainConnector opens the driver without registering it, which can only
+// be done once per process.
+type explainConnector struct{ driver *explainDriver }
+
+func (c explainConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c explainConnector) Driver() driver.Driver                        { return c.driver }
+
+type explainConn struct{ driver *explainDriver }
+
+func (c *explainConn) Prepare(query string) (driver.Stmt, error) {
+	return &explainStmt{c.driver, query}, nil
+}
+func (c *explainConn) Close() error              { return nil }
+func (c *explainConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type explainStmt struct {
+	driver *explainDriver
+	query  string
+}
+
+func (s *explainStmt) Close() error  { return nil }
+func (s *explainStmt) NumInput() int { return 0 }
+func (s *explainStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *explainStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.query = s.query
+	return &explainRows{lines: s.driver.plan}, nil
+}
+
+type explainRows struct{ lines []string }
+
+func (r *explainRows) Columns() []string { return []string{"QUERY PLAN"} }
+func (r *explainRows) Close() error      { return nil }
+
+func (r *explainRows) Next(dest []driver.Value) error {
+	if len(r.lines) == 0 {
+		return io.EOF
+	}
+
+	dest[0], r.lines = []byte(r.lines[0]), r.lines[1:]
+
+	return nil
+}
+
+func TestExplainQuery(t *testing.T) {
+	fake := &explainDriver{plan: []string{`[{"Plan": {"Node Type": "Result",`, ` "Total Cost": 0.01, "Plan Rows": 1}}]`}}
+	db := sql.OpenDB(explainConnector{fake})
+	defer db.Close()
+
+	tests := []struct {
+		analyze bool
+		want    string
+	}{
+		{false, "EXPLAIN (FORMAT JSON) SELECT 1"},
+		{true, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) SELECT 1"},
+	}
+
+	for _, test := range tests {
+		buffer, err := ExplainQuery(db, "SELECT 1", test.analyze)
+
+		if err != nil {
+			t.Fatalf("ExplainQuery: %v", err)
+		}
+
+		if fake.query != test.want {
+			t.Errorf("ExplainQuery ran %q, want %q", fake.query, test.want)
+		}
+
+		if explains, err := Analyze(buffer); err != nil || len(explains) != 1 || explains[0].Plan.NodeType != "Result" {
+			t.Errorf("ExplainQuery returned %q, which does not parse to the plan: %v", buffer, err)
+		}
+	}
+}
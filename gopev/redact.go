@@ -0,0 +1,111 @@
+package gopev
+
+import (
+	"strings"
+	"unicode"
+)
+
+// RedactLiterals replaces the string and numeric literals in a condition or
+// output expression with ?, leaving identifiers, operators and casts alone.
+// Escape strings such as E'it\'s' and numbers with an exponent such as
+// 3.5e10 are replaced whole.
+func RedactLiterals(expression string) string {
+	var redacted strings.Builder
+
+	runes := []rune(expression)
+
+	for index := 0; index < len(runes); index++ {
+		char := runes[index]
+
+		switch {
+		case char == '"':
+			end := index + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				end = len(runes) - 1
+			}
+			redacted.WriteString(string(runes[index : end+1]))
+			index = end
+		case char == '\'' || isEscapeStringStart(runes, index):
+			escapes := char != '\''
+			end := index + 1
+			if escapes {
+				end++
+			}
+			for end < len(runes) {
+				if escapes && runes[end] == '\\' {
+					end += 2
+					continue
+				}
+				if runes[end] == '\'' {
+					if end+1 < len(runes) && runes[end+1] == '\'' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			redacted.WriteRune('?')
+			index = end
+		case unicode.IsDigit(char) && (index == 0 || !isIdentifierRune(runes[index-1])):
+			end := index
+			for end+1 < len(runes) && (unicode.IsDigit(runes[end+1]) || runes[end+1] == '.') {
+				end++
+			}
+			if exponent := exponentLength(runes[end+1:]); exponent > 0 {
+				end += exponent
+			}
+			redacted.WriteRune('?')
+			index = end
+		default:
+			redacted.WriteRune(char)
+		}
+	}
+
+	return redacted.String()
+}
+
+// isEscapeStringStart reports whether an E'...' string, in which a backslash
+// escapes the next character, starts at index.
+func isEscapeStringStart(runes []rune, index int) bool {
+	if runes[index] != 'E' && runes[index] != 'e' {
+		return false
+	}
+
+	if index > 0 && isIdentifierRune(runes[index-1]) {
+		return false
+	}
+
+	return index+1 < len(runes) && runes[index+1] == '\''
+}
+
+// exponentLength is the length of the exponent, such as e10 or E-3, at the
+// start of runes, or 0 if there is none.
+func exponentLength(runes []rune) int {
+	if len(runes) < 2 || (runes[0] != 'e' && runes[0] != 'E') {
+		return 0
+	}
+
+	length := 1
+	if runes[1] == '+' || runes[1] == '-' {
+		length++
+	}
+
+	digits := length
+	for length < len(runes) && unicode.IsDigit(runes[length]) {
+		length++
+	}
+
+	if length == digits {
+		return 0
+	}
+
+	return length
+}
+
+func isIdentifierRune(char rune) bool {
+	return char == '_' || char == '$' || unicode.IsLetter(char) || unicode.IsDigit(char)
+}
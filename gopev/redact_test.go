@@ -0,0 +1,54 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactLiterals(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"(status = 'active'::text)", "(status = ?::text)"},
+		{"(name = 'O''Brien'::text)", "(name = ?::text)"},
+		{"(id = 42)", "(id = ?)"},
+		{"(price > 19.99)", "(price > ?)"},
+		{"(t1.a2 = 7)", "(t1.a2 = ?)"},
+		{`("Weird Col" = 'x'::text)`, `("Weird Col" = ?::text)`},
+		{`(note = E'it\'s secret'::text)`, "(note = ?::text)"},
+		{`(path = E'C:\\temp\\'::text)`, "(path = ?::text)"},
+		{`(x = e'a\'b' OR y = 1)`, "(x = ? OR y = ?)"},
+		{"(value > -3.5e10)", "(value > -?)"},
+		{"(value < 1E+3)", "(value < ?)"},
+		{"(value = 2e)", "(value = ?e)"},
+		{"(type = 'e')", "(type = ?)"},
+		{"(code = 'unterminated", "(code = ?"},
+	}
+
+	for _, test := range tests {
+		if got := RedactLiterals(test.expression); got != test.want {
+			t.Errorf("RedactLiterals(%q) = %q, want %q", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestRedactOption(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "users", "Schema": "public", "Filter": "(email = 'alice@example.com'::text)", "Output": ["id", "'secret'::text"], "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}]`)
+
+	if rendered := renderPlain(t, explain, Options{}); !strings.Contains(rendered, "alice@example.com") {
+		t.Errorf("literal redacted without Redact:\n%v", rendered)
+	}
+
+	rendered := renderPlain(t, explain, Options{Redact: true})
+
+	for _, literal := range []string{"alice@example.com", "secret"} {
+		if strings.Contains(rendered, literal) {
+			t.Errorf("output with Redact contains %q:\n%v", literal, rendered)
+		}
+	}
+
+	if explain.Plan.Output[1] != "'secret'::text" {
+		t.Errorf("Redact modified the plan output to %q", explain.Plan.Output[1])
+	}
+}
@@ -0,0 +1,150 @@
+package gopev
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RunStats summarizes a node's self-time over several runs of the same query.
+type RunStats struct {
+	Count  int
+	Mean   float64
+	Median float64
+	P95    float64
+	StdDev float64
+}
+
+func NewRunStats(durations []float64) *RunStats {
+	sorted := append([]float64(nil), durations...)
+	sort.Float64s(sorted)
+
+	stats := &RunStats{Count: len(sorted)}
+
+	if len(sorted) == 0 {
+		return stats
+	}
+
+	for _, duration := range sorted {
+		stats.Mean += duration
+	}
+	stats.Mean /= float64(len(sorted))
+
+	for _, duration := range sorted {
+		stats.StdDev += (duration - stats.Mean) * (duration - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(stats.StdDev / float64(len(sorted)))
+
+	stats.Median = percentile(sorted, 0.5)
+	stats.P95 = percentile(sorted, 0.95)
+
+	return stats
+}
+
+func percentile(sorted []float64, fraction float64) float64 {
+	position := fraction * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// AggregateExplains combines several runs of the same query into one plan
+// whose timings are the mean across runs, with each node's RunStats filled
+// in. The runs must have the same plan shape.
+func AggregateExplains(runs [][]byte) (*Explain, error) {
+	if len(runs) == 0 {
+		return nil, errors.New("no runs to aggregate")
+	}
+
+	var explains []*Explain
+
+	for index, run := range runs {
+		parsed, err := Analyze(run)
+
+		if err != nil {
+			return nil, fmt.Errorf("run %d: %v", index+1, err)
+		}
+
+		if len(parsed) == 0 {
+			return nil, fmt.Errorf("run %d: no plan", index+1)
+		}
+
+		if len(explains) > 0 && !samePlanShape(&explains[0].Plan, &parsed[0].Plan) {
+			return nil, fmt.Errorf("run %d: plan shape differs from run 1", index+1)
+		}
+
+		explains = append(explains, &parsed[0])
+	}
+
+	result := *explains[0]
+	result.Plan = copyPlan(&explains[0].Plan)
+
+	var plans [][]*Plan
+
+	for _, explain := range explains {
+		var nodes []*Plan
+		CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+			nodes = append(nodes, plan)
+		})
+		plans = append(plans, nodes)
+	}
+
+	var nodes []*Plan
+	CollectPlans(&result.Plan, func(plan *Plan, path []*Plan) {
+		nodes = append(nodes, plan)
+	})
+
+	count := float64(len(explains))
+
+	result.PlanningTime, result.ExecutionTime = 0, 0
+
+	for _, explain := range explains {
+		result.PlanningTime += explain.PlanningTime / count
+		result.ExecutionTime += explain.ExecutionTime / count
+	}
+
+	for index, node := range nodes {
+		node.ActualStartupTime, node.ActualTotalTime = 0, 0
+
+		var durations []float64
+
+		for run := range explains {
+			node.ActualStartupTime += plans[run][index].ActualStartupTime / count
+			node.ActualTotalTime += plans[run][index].ActualTotalTime / count
+			durations = append(durations, plans[run][index].ActualDuration)
+		}
+
+		node.Runs = NewRunStats(durations)
+	}
+
+	ProcessExplain(&result)
+
+	return &result, nil
+}
+
+func samePlanShape(a, b *Plan) bool {
+	if a.NodeType != b.NodeType || a.RelationName != b.RelationName || len(a.Plans) != len(b.Plans) {
+		return false
+	}
+
+	for index, _ := range a.Plans {
+		if !samePlanShape(&a.Plans[index], &b.Plans[index]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func copyPlan(plan *Plan) Plan {
+	copied := *plan
+	copied.Plans = make([]Plan, len(plan.Plans))
+
+	for index, _ := range plan.Plans {
+		copied.Plans[index] = copyPlan(&plan.Plans[index])
+	}
+
+	return copied
+}
@@ -0,0 +1,49 @@
+package gopev
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNewRunStats(t *testing.T) {
+	stats := NewRunStats([]float64{40, 10, 30, 20})
+
+	if stats.Count != 4 || stats.Mean != 25 || stats.Median != 25 || math.Abs(stats.P95-38.5) > 1e-9 || math.Abs(stats.StdDev-math.Sqrt(125)) > 1e-9 {
+		t.Errorf("NewRunStats = %+v", *stats)
+	}
+
+	if empty := NewRunStats(nil); empty.Count != 0 || empty.Mean != 0 {
+		t.Errorf("NewRunStats(nil) = %+v, want zero", *empty)
+	}
+}
+
+func run(seconds float64, relation string) []byte {
+	return []byte(fmt.Sprintf(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": %q, "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": %v, "Actual Rows": 1, "Actual Loops": 1}, "Planning Time": 1, "Execution Time": %v}]`, relation, seconds, seconds))
+}
+
+func TestAggregateExplains(t *testing.T) {
+	explain, err := AggregateExplains([][]byte{run(10, "t"), run(20, "t"), run(60, "t")})
+
+	if err != nil {
+		t.Fatalf("AggregateExplains: %v", err)
+	}
+
+	if explain.ExecutionTime != 30 || explain.Plan.ActualTotalTime != 30 || explain.Plan.ActualDuration != 30 {
+		t.Errorf("aggregated times = %v, %v, %v, want the mean 30", explain.ExecutionTime, explain.Plan.ActualTotalTime, explain.Plan.ActualDuration)
+	}
+
+	if runs := explain.Plan.Runs; runs == nil || runs.Count != 3 || runs.Median != 20 {
+		t.Errorf("Runs = %+v, want 3 runs with median 20", runs)
+	}
+}
+
+func TestAggregateExplainsErrors(t *testing.T) {
+	if _, err := AggregateExplains(nil); err == nil {
+		t.Errorf("AggregateExplains of no runs returned no error")
+	}
+
+	if _, err := AggregateExplains([][]byte{run(10, "t"), run(20, "u")}); err == nil || err.Error() != "run 2: plan shape differs from run 1" {
+		t.Errorf("AggregateExplains of different shapes = %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package gopev
+
+// PlanScore grades the health of a processed plan from 0 to 100. It starts
+// at 100 and deducts, with each signal capped so no single one dominates:
+//
+//   - estimates: 15 per node with a bad estimate (see IsBadEstimate), 5 per
+//     node off by 10x (up to 30)
+//   - disk spills: 10 per DiskSpill warning (up to 20)
+//   - sequential scans: 10 per FilteredSeqScan warning (up to 20)
+//   - cache hits: 10 per LowCacheHit warning (up to 20)
+//   - time concentration: 10 when a DominantNode accounts for DominantShare
+//     (80%) or more of the execution time
+func PlanScore(explain *Explain) int {
+	return planScore(explain, Options{})
+}
+
+// planScore is PlanScore counting the opt-in hints enabled in options.
+func planScore(explain *Explain, options Options) int {
+	var estimates, spills, scans, cache int
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		if IsBadEstimate(plan) {
+			estimates += 15
+		} else if plan.PlannerRowEstimateFactor >= 10 {
+			estimates += 5
+		}
+	})
+
+	for _, warning := range collectWarnings(explain, options) {
+		switch warning.Kind {
+		case DiskSpill:
+			spills += 10
+		case FilteredSeqScan:
+			scans += 10
+		case LowCacheHit:
+			cache += 10
+		}
+	}
+
+	score := 100 - capScore(estimates, 30) - capScore(spills, 20) - capScore(scans, 20) - capScore(cache, 20)
+
+	if DominantNode(explain) != nil {
+		score -= 10
+	}
+
+	if score < 0 {
+		score = 0
+	}
+
+	return score
+}
+
+func capScore(deduction int, limit int) int {
+	if deduction > limit {
+		return limit
+	}
+
+	return deduction
+}
+
+// ScoreFormat colors a score: good from 80, a warning from 50, critical
+// below that.
+func ScoreFormat(score int) Format {
+	if score >= 80 {
+		return GoodFormat
+	} else if score >= 50 {
+		return WarningFormat
+	}
+
+	return CriticalFormat
+}
@@ -0,0 +1,41 @@
+package gopev
+
+import (
+	"testing"
+)
+
+func TestPlanScore(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Sort", "Sort Key": ["t.a"], "Sort Method": "external merge", "Sort Space Used": 20000, "Sort Space Type": "Disk", "Total Cost": 100, "Plan Rows": 10, "Actual Total Time": 60, "Actual Rows": 100000, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(b = 1)", "Rows Removed by Filter": 900000, "Total Cost": 90, "Plan Rows": 10, "Actual Total Time": 30, "Actual Rows": 100000, "Actual Loops": 1}]},
+		"Execution Time": 60}]`)
+
+	// Two bad estimates (capped at 30), a disk spill and a filtered scan.
+	if got := PlanScore(explain); got != 50 {
+		t.Errorf("PlanScore = %v, want 50", got)
+	}
+
+	// The Nested Loop dominates the execution time.
+	if got := PlanScore(analyzeOne(t, nestedLoopFunctionScan)); got != 90 {
+		t.Errorf("PlanScore of a dominated plan = %v, want 90", got)
+	}
+}
+
+func TestCapScore(t *testing.T) {
+	if capScore(45, 30) != 30 || capScore(20, 30) != 20 {
+		t.Errorf("capScore = %v, %v, want 30, 20", capScore(45, 30), capScore(20, 30))
+	}
+}
+
+func TestScoreFormat(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	labels := Theme{Good: labeled("good"), Warning: labeled("warning"), Critical: labeled("critical")}
+	labels.Apply()
+
+	for score, want := range map[int]string{100: "good(x)", 80: "good(x)", 79: "warning(x)", 50: "warning(x)", 49: "critical(x)"} {
+		if got := ScoreFormat(score)("x"); got != want {
+			t.Errorf("ScoreFormat(%v) = %q, want %q", score, got, want)
+		}
+	}
+}
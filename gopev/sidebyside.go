@@ -0,0 +1,106 @@
+package gopev
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mattn/go-runewidth"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// VisualizeSideBySide renders two plans in columns next to each other,
+// width characters wide in total, for comparing them. Each side shows the
+// first plan in its buffer; lines too long for a column are cut.
+func VisualizeSideBySide(writer io.Writer, left, right []byte, width int) error {
+	columns := make([][]string, 2)
+
+	for index, buffer := range [][]byte{left, right} {
+		explains, err := Analyze(buffer)
+
+		if err != nil {
+			return err
+		}
+
+		if len(explains) == 0 {
+			continue
+		}
+
+		var rendered bytes.Buffer
+
+		err = WriteExplain(&rendered, &explains[0], Options{HideDescriptions: true})
+
+		if err != nil {
+			return err
+		}
+
+		columns[index] = strings.Split(strings.TrimRight(rendered.String(), "\n"), "\n")
+	}
+
+	separator := " " + Glyphs.Vertical + " "
+	column := (width - runewidth.StringWidth(separator)) / 2
+
+	if column < 1 {
+		column = 1
+	}
+
+	height := len(columns[0])
+	if len(columns[1]) > height {
+		height = len(columns[1])
+	}
+
+	for index := 0; index < height; index++ {
+		var cells [2]string
+
+		for side, lines := range columns {
+			if index < len(lines) {
+				cells[side] = lines[index]
+			}
+		}
+
+		_, err := fmt.Fprintf(writer, "%v%v%v\n", fitColumn(cells[0], column), PrefixFormat(separator), strings.TrimRight(fitColumn(cells[1], column), " "))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fitColumn cuts or pads line to width terminal cells, passing ANSI escape
+// sequences through without counting them.
+func fitColumn(line string, width int) string {
+	var fitted strings.Builder
+	visible, escaped := 0, false
+
+	for index := 0; index < len(line); {
+		if line[index] == '\x1b' {
+			match := ansiPattern.FindStringIndex(line[index:])
+			if match == nil || match[0] != 0 {
+				break
+			}
+			fitted.WriteString(line[index : index+match[1]])
+			escaped = true
+			index += match[1]
+			continue
+		}
+
+		char, size := utf8.DecodeRuneInString(line[index:])
+		cells := runewidth.RuneWidth(char)
+
+		if visible+cells > width {
+			break
+		}
+
+		fitted.WriteString(line[index : index+size])
+		visible += cells
+		index += size
+	}
+
+	if escaped {
+		fitted.WriteString("\x1b[0m")
+	}
+
+	return fitted.String() + strings.Repeat(" ", width-visible)
+}
@@ -0,0 +1,61 @@
+package gopev
+
+import (
+	"github.com/mattn/go-runewidth"
+	"strings"
+	"testing"
+)
+
+func TestVisualizeSideBySide(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	right := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}]`
+
+	var written strings.Builder
+
+	if err := VisualizeSideBySide(&written, []byte(nestedLoopFunctionScan), []byte(right), 80); err != nil {
+		t.Fatalf("VisualizeSideBySide: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(written.String(), "\n"), "\n")
+
+	if len(lines) != len(strings.Split(strings.TrimRight(renderPlain(t, analyzeOne(t, nestedLoopFunctionScan), Options{HideDescriptions: true}), "\n"), "\n")) {
+		t.Errorf("VisualizeSideBySide wrote %d lines, want as many as the taller plan", len(lines))
+	}
+
+	for _, line := range lines {
+		if width := runewidth.StringWidth(line); width > 80 {
+			t.Errorf("line %q is %d cells wide, want at most 80", line, width)
+		}
+
+		if !strings.HasPrefix(string([]rune(line)[38:]), " │ ") {
+			t.Errorf("separator not at column 38: %q", line)
+		}
+	}
+
+	if !strings.HasPrefix(lines[0], "○ Total Cost: 1,000") || !strings.HasSuffix(lines[0], "○ Total Cost: 10") {
+		t.Errorf("first line = %q, want both plans' total costs", lines[0])
+	}
+}
+
+func TestFitColumn(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abcd"},
+		{"表格表格", 5, "表格 "},
+		{"\x1b[31mred\x1b[0m", 2, "\x1b[31mre\x1b[0m"},
+	}
+
+	for _, test := range tests {
+		if got := fitColumn(test.line, test.width); got != test.want {
+			t.Errorf("fitColumn(%q, %d) = %q, want %q", test.line, test.width, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package gopev
+
+import (
+	"context"
+	"log/slog"
+)
+
+// VisualizeSlog logs one record per node of each plan in buffer instead of
+// drawing a tree. Nodes rated a warning are logged at slog.LevelWarn and
+// critical ones at slog.LevelError.
+func VisualizeSlog(logger *slog.Logger, buffer []byte) error {
+	explains, err := Analyze(buffer)
+
+	if err != nil {
+		return err
+	}
+
+	for index, _ := range explains {
+		explain := &explains[index]
+
+		CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+			attributes := []slog.Attr{
+				slog.Int("id", plan.ID),
+				slog.Int("depth", len(path)),
+				slog.String("type", string(plan.NodeType)),
+				slog.Float64("cost", plan.ActualCost),
+			}
+
+			if !explain.EstimateOnly {
+				attributes = append(attributes, slog.Float64("self_ms", plan.ActualDuration), slog.Uint64("rows", plan.ActualRows))
+			} else {
+				attributes = append(attributes, slog.Uint64("rows", plan.PlanRows))
+			}
+
+			if plan.RelationName != "" {
+				attributes = append(attributes, slog.String("relation", plan.Schema+"."+plan.RelationName))
+			}
+
+			if tags := PlanTags(plan); len(tags) > 0 {
+				attributes = append(attributes, slog.Any("tags", tags))
+			}
+
+			level := slog.LevelInfo
+
+			switch NodeSeverity(explain, plan) {
+			case SeverityWarning:
+				level = slog.LevelWarn
+			case SeverityCritical:
+				level = slog.LevelError
+			}
+
+			logger.LogAttrs(context.Background(), level, "plan node", attributes...)
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package gopev
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestVisualizeSlog(t *testing.T) {
+	var logged bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if err := VisualizeSlog(logger, []byte(nestedLoopFunctionScan)); err != nil {
+		t.Fatalf("VisualizeSlog: %v", err)
+	}
+
+	var records []map[string]interface{}
+
+	for _, line := range strings.Split(strings.TrimSpace(logged.String()), "\n") {
+		var record map[string]interface{}
+
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("record %q: %v", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("VisualizeSlog logged %d records, want 3", len(records))
+	}
+
+	want := []struct {
+		id       float64
+		depth    float64
+		nodeType string
+		level    string
+	}{
+		{1, 0, "Nested Loop", "WARN"},
+		{2, 1, "Seq Scan", "INFO"},
+		{3, 1, "Function Scan", "WARN"},
+	}
+
+	for index, expected := range want {
+		record := records[index]
+
+		if record["id"] != expected.id || record["depth"] != expected.depth || record["type"] != expected.nodeType || record["level"] != expected.level {
+			t.Errorf("record %d = %v, want id %v, depth %v, %v at %v", index, record, expected.id, expected.depth, expected.nodeType, expected.level)
+		}
+	}
+
+	if records[1]["relation"] != "public.a" {
+		t.Errorf("Seq Scan relation = %v, want public.a", records[1]["relation"])
+	}
+}
+
+func TestVisualizeSlogInvalidJSON(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	if err := VisualizeSlog(logger, []byte(`[{"Plan": `)); err == nil {
+		t.Errorf("VisualizeSlog of truncated JSON returned no error")
+	}
+}
@@ -0,0 +1,109 @@
+package gopev
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SortSpace struct {
+	AverageSpaceUsed uint64 `json:"Average Sort Space Used"`
+	PeakSpaceUsed    uint64 `json:"Peak Sort Space Used"`
+}
+
+// SortGroups is reported by Incremental Sort for the groups it sorted in
+// full and the groups it only had to sort on the remaining keys.
+type SortGroups struct {
+	GroupCount      uint64     `json:"Group Count"`
+	SortMethodsUsed []string   `json:"Sort Methods Used"`
+	SortSpaceMemory *SortSpace `json:"Sort Space Memory"`
+	SortSpaceDisk   *SortSpace `json:"Sort Space Disk"`
+}
+
+// SortDetail is the sort method and space usage of a Sort or Incremental
+// Sort, whichever way the node reported it. SpaceUsed is in kB.
+type SortDetail struct {
+	Key          []string
+	PresortedKey []string
+	Methods      []string
+	SpaceType    string
+	SpaceUsed    uint64
+}
+
+func GetSortDetail(plan *Plan) SortDetail {
+	detail := SortDetail{
+		Key:          plan.SortKey,
+		PresortedKey: plan.PresortedKey,
+	}
+
+	if plan.SortMethod != "" {
+		detail.Methods = append(detail.Methods, plan.SortMethod)
+	}
+
+	detail.SpaceType = plan.SortSpaceType
+	detail.SpaceUsed = plan.SortSpaceUsed
+
+	for _, groups := range []*SortGroups{plan.FullSortGroups, plan.PreSortedGroups} {
+		if groups == nil {
+			continue
+		}
+
+		for _, method := range groups.SortMethodsUsed {
+			if !containsString(detail.Methods, method) {
+				detail.Methods = append(detail.Methods, method)
+			}
+		}
+
+		if groups.SortSpaceDisk != nil {
+			if detail.SpaceType != "Disk" {
+				detail.SpaceType = "Disk"
+				detail.SpaceUsed = 0
+			}
+			if groups.SortSpaceDisk.PeakSpaceUsed > detail.SpaceUsed {
+				detail.SpaceUsed = groups.SortSpaceDisk.PeakSpaceUsed
+			}
+		} else if groups.SortSpaceMemory != nil && detail.SpaceType != "Disk" {
+			detail.SpaceType = "Memory"
+			if groups.SortSpaceMemory.PeakSpaceUsed > detail.SpaceUsed {
+				detail.SpaceUsed = groups.SortSpaceMemory.PeakSpaceUsed
+			}
+		}
+	}
+
+	return detail
+}
+
+func formatSortDetail(detail SortDetail) string {
+	var parts []string
+
+	if len(detail.Key) > 0 {
+		parts = append(parts, fmt.Sprintf("%v %v", MutedFormat("by"), strings.Join(detail.Key, ", ")))
+	}
+
+	if len(detail.PresortedKey) > 0 {
+		parts = append(parts, fmt.Sprintf("%v %v", MutedFormat("presorted"), strings.Join(detail.PresortedKey, ", ")))
+	}
+
+	if len(detail.Methods) > 0 {
+		parts = append(parts, strings.Join(detail.Methods, ", "))
+	}
+
+	if detail.SpaceType != "" {
+		space := fmt.Sprintf("%v %v", FormatBytes(detail.SpaceUsed*1024), strings.ToLower(detail.SpaceType))
+		if detail.SpaceType == "Disk" {
+			space = WarningFormat(space)
+		}
+		parts = append(parts, space)
+	}
+
+	return strings.Join(parts, MutedFormat(", "))
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
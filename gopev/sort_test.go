@@ -0,0 +1,58 @@
+package gopev
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetSortDetail(t *testing.T) {
+	tests := []struct {
+		name string
+		plan Plan
+		want SortDetail
+	}{
+		{
+			"sort in memory",
+			Plan{NodeType: Sort, SortKey: []string{"a"}, SortMethod: "quicksort", SortSpaceType: "Memory", SortSpaceUsed: 25},
+			SortDetail{Key: []string{"a"}, Methods: []string{"quicksort"}, SpaceType: "Memory", SpaceUsed: 25},
+		},
+		{
+			"sort on disk",
+			Plan{NodeType: Sort, SortKey: []string{"a"}, SortMethod: "external merge", SortSpaceType: "Disk", SortSpaceUsed: 9000},
+			SortDetail{Key: []string{"a"}, Methods: []string{"external merge"}, SpaceType: "Disk", SpaceUsed: 9000},
+		},
+		{
+			"incremental sort in memory",
+			Plan{NodeType: IncrementalSort, SortKey: []string{"a", "b"}, PresortedKey: []string{"a"},
+				FullSortGroups:  &SortGroups{SortMethodsUsed: []string{"quicksort"}, SortSpaceMemory: &SortSpace{PeakSpaceUsed: 30}},
+				PreSortedGroups: &SortGroups{SortMethodsUsed: []string{"quicksort", "top-N heapsort"}, SortSpaceMemory: &SortSpace{PeakSpaceUsed: 40}}},
+			SortDetail{Key: []string{"a", "b"}, PresortedKey: []string{"a"}, Methods: []string{"quicksort", "top-N heapsort"}, SpaceType: "Memory", SpaceUsed: 40},
+		},
+		{
+			"incremental sort spilling",
+			Plan{NodeType: IncrementalSort, SortKey: []string{"a", "b"}, PresortedKey: []string{"a"},
+				FullSortGroups:  &SortGroups{SortMethodsUsed: []string{"quicksort"}, SortSpaceMemory: &SortSpace{PeakSpaceUsed: 300}},
+				PreSortedGroups: &SortGroups{SortMethodsUsed: []string{"external merge"}, SortSpaceDisk: &SortSpace{PeakSpaceUsed: 200}}},
+			SortDetail{Key: []string{"a", "b"}, PresortedKey: []string{"a"}, Methods: []string{"quicksort", "external merge"}, SpaceType: "Disk", SpaceUsed: 200},
+		},
+	}
+
+	for _, test := range tests {
+		if got := GetSortDetail(&test.plan); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: GetSortDetail = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFormatSortDetail(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	detail := SortDetail{Key: []string{"a", "b"}, PresortedKey: []string{"a"}, Methods: []string{"quicksort"}, SpaceType: "Disk", SpaceUsed: 2048}
+
+	if got, want := formatSortDetail(detail), "by a, b, presorted a, quicksort, 2.1 MB disk"; got != want {
+		t.Errorf("formatSortDetail = %q, want %q", got, want)
+	}
+}
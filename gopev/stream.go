@@ -0,0 +1,40 @@
+package gopev
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// VisualizeStream reads successive top-level JSON values from reader, as
+// found when several plans are concatenated in a log, and renders each one.
+// A value may be an EXPLAIN array or a single plan object.
+func VisualizeStream(writer io.Writer, reader io.Reader, options Options) error {
+	decoder := json.NewDecoder(reader)
+
+	for {
+		var value json.RawMessage
+
+		err := decoder.Decode(&value)
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		value = bytes.TrimSpace(value)
+
+		if len(value) > 0 && value[0] == '{' {
+			value = append(append([]byte("["), value...), ']')
+		}
+
+		err = VisualizeWithOptions(writer, value, options)
+
+		if err != nil {
+			return err
+		}
+	}
+}
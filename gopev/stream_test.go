@@ -0,0 +1,33 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVisualizeStream(t *testing.T) {
+	plan := `{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 10, "Plan Rows": 1, "Actual Total Time": 1, "Actual Rows": 1, "Actual Loops": 1}, "Execution Time": 1}`
+	stream := "[" + plan + "]\n" + plan + "\n\n[" + plan + "," + plan + "]"
+
+	var rendered strings.Builder
+
+	if err := VisualizeStream(&rendered, strings.NewReader(stream), Options{Theme: &NoColorTheme}); err != nil {
+		t.Fatalf("VisualizeStream: %v", err)
+	}
+
+	if got := strings.Count(rendered.String(), "○ Total Cost:"); got != 4 {
+		t.Errorf("VisualizeStream rendered %d plans, want 4:\n%v", got, rendered.String())
+	}
+}
+
+func TestVisualizeStreamError(t *testing.T) {
+	var rendered strings.Builder
+
+	if err := VisualizeStream(&rendered, strings.NewReader(`[{"Plan": {}}] {"Plan": `), Options{Theme: &NoColorTheme}); err == nil {
+		t.Errorf("VisualizeStream of a truncated stream returned no error")
+	}
+
+	if !strings.Contains(rendered.String(), "○ Total Cost:") {
+		t.Errorf("VisualizeStream did not render the plan before the error")
+	}
+}
@@ -0,0 +1,54 @@
+package gopev
+
+// Symbols are the glyphs used to draw the tree. Bullet marks metric and
+// summary lines; the rest draw the tree's lines and junctions and should be
+// a single column wide (Node two) so the tree stays aligned.
+type Symbols struct {
+	Bullet      string
+	Root        string
+	Vertical    string
+	Branch      string
+	LastBranch  string
+	Node        string
+	OutputFirst string
+	OutputOnly  string
+}
+
+var UnicodeSymbols = Symbols{
+	Bullet:      "○",
+	Root:        "┬",
+	Vertical:    "│",
+	Branch:      "├",
+	LastBranch:  "└",
+	Node:        "─⌠",
+	OutputFirst: "├►",
+	OutputOnly:  "⌡►",
+}
+
+var ASCIISymbols = Symbols{
+	Bullet:      "*",
+	Root:        "+",
+	Vertical:    "|",
+	Branch:      "+",
+	LastBranch:  "`",
+	Node:        "-[",
+	OutputFirst: "+>",
+	OutputOnly:  "`>",
+}
+
+// Glyphs are the symbols the renderer currently draws with. Applying a
+// Symbols replaces them.
+var Glyphs = UnicodeSymbols
+
+// Apply makes symbols the package's Glyphs. It waits for a WriteExplain in
+// progress to put its own glyphs back first.
+func (symbols *Symbols) Apply() {
+	renderLock.Lock()
+	defer renderLock.Unlock()
+
+	symbols.apply()
+}
+
+func (symbols *Symbols) apply() {
+	Glyphs = *symbols
+}
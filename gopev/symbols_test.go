@@ -0,0 +1,51 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestASCIISymbols(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	rendered := renderPlain(t, explain, Options{Symbols: &ASCIISymbols})
+
+	unicode := UnicodeSymbols
+
+	for _, glyph := range []string{unicode.Bullet, unicode.Root, unicode.Vertical, unicode.Branch, unicode.LastBranch, unicode.Node, unicode.OutputFirst, unicode.OutputOnly} {
+		if strings.Contains(rendered, glyph) {
+			t.Errorf("output with ASCIISymbols contains %q:\n%v", glyph, rendered)
+		}
+	}
+
+	for _, want := range []string{"* Total Cost:", "+-[ #2 outer Seq Scan", "`-[ #3 inner Function Scan"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("output missing %q:\n%v", want, rendered)
+		}
+	}
+
+	if Glyphs != UnicodeSymbols {
+		t.Errorf("Options.Symbols left Glyphs changed to %+v", Glyphs)
+	}
+}
+
+func TestSymbolsApplyDuringRender(t *testing.T) {
+	defer UnicodeSymbols.Apply()
+
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	done := make(chan string)
+
+	go func() {
+		var rendered strings.Builder
+		WriteExplain(&rendered, explain, Options{Theme: &NoColorTheme, Symbols: &ASCIISymbols})
+		done <- rendered.String()
+	}()
+
+	for index := 0; index < 100; index++ {
+		UnicodeSymbols.Apply()
+	}
+
+	if rendered := <-done; strings.Contains(rendered, UnicodeSymbols.Node) {
+		t.Errorf("render with ASCIISymbols picked up Unicode glyphs from an Apply:\n%v", rendered)
+	}
+}
@@ -0,0 +1,110 @@
+package gopev
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+)
+
+type Format func(a ...interface{}) string
+
+// A Theme is a set of Format functions. Applying it replaces the package
+// level formats used by the renderer.
+type Theme struct {
+	Prefix   Format
+	Tag      Format
+	Muted    Format
+	Bold     Format
+	Good     Format
+	Warning  Format
+	Critical Format
+	Output   Format
+}
+
+var DarkTheme = Theme{
+	Prefix:   color.New(color.FgHiBlack).SprintFunc(),
+	Tag:      color.New(color.FgWhite, color.BgRed).SprintFunc(),
+	Muted:    color.New(color.FgHiBlack).SprintFunc(),
+	Bold:     color.New(color.FgHiWhite).SprintFunc(),
+	Good:     color.New(color.FgGreen).SprintFunc(),
+	Warning:  color.New(color.FgHiYellow).SprintFunc(),
+	Critical: color.New(color.FgHiRed).SprintFunc(),
+	Output:   color.New(color.FgCyan).SprintFunc(),
+}
+
+var LightTheme = Theme{
+	Prefix:   color.New(color.FgBlue).SprintFunc(),
+	Tag:      color.New(color.FgHiWhite, color.BgRed).SprintFunc(),
+	Muted:    color.New(color.FgBlue).SprintFunc(),
+	Bold:     color.New(color.FgBlack, color.Bold).SprintFunc(),
+	Good:     color.New(color.FgGreen).SprintFunc(),
+	Warning:  color.New(color.FgYellow).SprintFunc(),
+	Critical: color.New(color.FgRed).SprintFunc(),
+	Output:   color.New(color.FgMagenta).SprintFunc(),
+}
+
+var NoColorTheme = Theme{
+	Prefix:   fmt.Sprint,
+	Tag:      func(a ...interface{}) string { return "[" + fmt.Sprint(a...) + "]" },
+	Muted:    fmt.Sprint,
+	Bold:     fmt.Sprint,
+	Good:     fmt.Sprint,
+	Warning:  fmt.Sprint,
+	Critical: fmt.Sprint,
+	Output:   fmt.Sprint,
+}
+
+// ColorBlindTheme avoids the red/green scale: severities are blue, yellow
+// and magenta, and each is marked with a symbol so they can be told apart
+// without color at all.
+var ColorBlindTheme = Theme{
+	Prefix:   color.New(color.FgHiBlack).SprintFunc(),
+	Tag:      color.New(color.FgHiWhite, color.BgBlue).SprintFunc(),
+	Muted:    color.New(color.FgHiBlack).SprintFunc(),
+	Bold:     color.New(color.FgHiWhite).SprintFunc(),
+	Good:     WithSymbol("✓", color.New(color.FgBlue).SprintFunc()),
+	Warning:  WithSymbol("⚠", color.New(color.FgHiYellow).SprintFunc()),
+	Critical: WithSymbol("✗", color.New(color.FgHiMagenta).SprintFunc()),
+	Output:   color.New(color.FgCyan).SprintFunc(),
+}
+
+// WithSymbol returns a Format that prefixes its output with symbol.
+func WithSymbol(symbol string, format Format) Format {
+	return func(a ...interface{}) string {
+		return format(symbol + " " + fmt.Sprint(a...))
+	}
+}
+
+// Apply makes theme the package's formats. It waits for a WriteExplain in
+// progress to put its own formats back first.
+func (theme *Theme) Apply() {
+	renderLock.Lock()
+	defer renderLock.Unlock()
+
+	theme.apply()
+}
+
+func (theme *Theme) apply() {
+	PrefixFormat = theme.Prefix
+	TagFormat = theme.Tag
+	MutedFormat = theme.Muted
+	BoldFormat = theme.Bold
+	GoodFormat = theme.Good
+	WarningFormat = theme.Warning
+	CriticalFormat = theme.Critical
+	OutputFormat = theme.Output
+}
+
+// currentTheme is the Theme made of the formats in use, so they can be put
+// back after a render that applied another.
+func currentTheme() Theme {
+	return Theme{
+		Prefix:   PrefixFormat,
+		Tag:      TagFormat,
+		Muted:    MutedFormat,
+		Bold:     BoldFormat,
+		Good:     GoodFormat,
+		Warning:  WarningFormat,
+		Critical: CriticalFormat,
+		Output:   OutputFormat,
+	}
+}
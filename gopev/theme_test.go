@@ -0,0 +1,85 @@
+package gopev
+
+import (
+	"github.com/fatih/color"
+	"strings"
+	"testing"
+)
+
+func TestThemeApply(t *testing.T) {
+	previous := currentTheme()
+	defer previous.Apply()
+
+	NoColorTheme.Apply()
+
+	if got := FormatTag("slowest"); got != "[ slowest ]" {
+		t.Errorf("FormatTag with NoColorTheme = %q, want %q", got, "[ slowest ]")
+	}
+
+	if got := CriticalFormat("x"); got != "x" {
+		t.Errorf("CriticalFormat with NoColorTheme = %q, want %q", got, "x")
+	}
+}
+
+func TestThemesColor(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	color.NoColor = false
+
+	for name, theme := range map[string]Theme{"dark": DarkTheme, "light": LightTheme, "color-blind": ColorBlindTheme} {
+		if got := StripANSI(theme.Critical("x")); got == theme.Critical("x") {
+			t.Errorf("%v theme does not color critical text: %q", name, got)
+		}
+	}
+}
+
+func TestWithSymbol(t *testing.T) {
+	format := WithSymbol("⚠", func(a ...interface{}) string { return "<" + a[0].(string) + ">" })
+
+	if got := format("slow"); got != "<⚠ slow>" {
+		t.Errorf("WithSymbol = %q, want %q", got, "<⚠ slow>")
+	}
+}
+
+func TestColorBlindThemeSymbols(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	color.NoColor = true
+
+	for _, test := range []struct {
+		format Format
+		want   string
+	}{
+		{ColorBlindTheme.Good, "✓ x"},
+		{ColorBlindTheme.Warning, "⚠ x"},
+		{ColorBlindTheme.Critical, "✗ x"},
+	} {
+		if got := test.format("x"); got != test.want {
+			t.Errorf("ColorBlindTheme severity = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestThemeApplyDuringRender(t *testing.T) {
+	previous := currentTheme()
+	defer previous.Apply()
+
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+	done := make(chan string)
+
+	go func() {
+		var rendered strings.Builder
+		WriteExplain(&rendered, explain, Options{Theme: &NoColorTheme})
+		done <- rendered.String()
+	}()
+
+	for index := 0; index < 100; index++ {
+		LightTheme.Apply()
+	}
+
+	if rendered := <-done; strings.Contains(rendered, "\x1b[") {
+		t.Errorf("render with NoColorTheme picked up colors from an Apply:\n%q", rendered)
+	}
+}
@@ -0,0 +1,101 @@
+package gopev
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TopNodesByDuration returns up to n nodes ordered by descending self-time,
+// or every node when n is negative. Nodes with equal times keep their order
+// in the tree.
+func TopNodesByDuration(explain *Explain, n int) []*Plan {
+	var nodes []*Plan
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		nodes = append(nodes, plan)
+	})
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].ActualDuration > nodes[j].ActualDuration
+	})
+
+	if n >= 0 && n < len(nodes) {
+		nodes = nodes[:n]
+	}
+
+	return nodes
+}
+
+// CollectPlans calls visit for every node in pre-order along with the path
+// of ancestors leading to it. The path is only valid during the call.
+func CollectPlans(plan *Plan, visit func(plan *Plan, path []*Plan)) {
+	collectPlans(plan, nil, visit)
+}
+
+func collectPlans(plan *Plan, path []*Plan, visit func(plan *Plan, path []*Plan)) {
+	visit(plan, path)
+
+	path = append(path, plan)
+
+	for index, _ := range plan.Plans {
+		collectPlans(&plan.Plans[index], path, visit)
+	}
+}
+
+// CollectIndexes returns the distinct names of the indexes the plan uses, in
+// the order they first appear.
+func CollectIndexes(explain *Explain) []string {
+	var indexes []string
+	seen := map[string]bool{}
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		if plan.IndexName != "" && !seen[plan.IndexName] {
+			seen[plan.IndexName] = true
+			indexes = append(indexes, plan.IndexName)
+		}
+	})
+
+	return indexes
+}
+
+func WriteTopNodes(writer io.Writer, explain *Explain, n int) {
+	paths := map[*Plan]string{}
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		var names []string
+		for _, parent := range path {
+			names = append(names, string(parent.NodeType))
+		}
+		paths[plan] = strings.Join(append(names, string(plan.NodeType)), " › ")
+	})
+
+	fmt.Fprintf(writer, "%v Slowest Nodes:\n", Glyphs.Bullet)
+
+	for index, plan := range TopNodesByDuration(explain, n) {
+		location := fmt.Sprintf("%v %v", MutedFormat(fmt.Sprintf("#%d", plan.ID)), paths[plan])
+		if plan.RelationName != "" {
+			location = fmt.Sprintf("%v %v %v.%v", location, MutedFormat("on"), plan.Schema, plan.RelationName)
+		}
+		fmt.Fprintf(writer, "  %v %v (%.0f%%) %v\n", PrefixFormat(fmt.Sprintf("%d.", index+1)), DurationToString(plan.ActualDuration), DurationShare(explain, plan)*100, location)
+	}
+}
+
+// CalculateCostRanks numbers the nodes from the highest own cost down,
+// starting at 1, keeping plan order between nodes of equal cost.
+func CalculateCostRanks(explain *Explain) {
+	var nodes []*Plan
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		nodes = append(nodes, plan)
+	})
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].ActualCost > nodes[j].ActualCost
+	})
+
+	for index, plan := range nodes {
+		plan.CostRank = index + 1
+	}
+}
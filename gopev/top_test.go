@@ -0,0 +1,102 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopNodesByDurationBounds(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	tests := []struct {
+		n    int
+		want []int
+	}{
+		{-1, []int{1, 3, 2}},
+		{0, []int{}},
+		{2, []int{1, 3}},
+		{3, []int{1, 3, 2}},
+		{10, []int{1, 3, 2}},
+	}
+
+	for _, test := range tests {
+		nodes := TopNodesByDuration(explain, test.n)
+
+		var ids []int
+		for _, node := range nodes {
+			ids = append(ids, node.ID)
+		}
+
+		if len(ids) != len(test.want) {
+			t.Errorf("TopNodesByDuration(%d) = %v, want %v", test.n, ids, test.want)
+			continue
+		}
+
+		for index, _ := range ids {
+			if ids[index] != test.want[index] {
+				t.Errorf("TopNodesByDuration(%d) = %v, want %v", test.n, ids, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestWriteTopNodes(t *testing.T) {
+	theme := currentTheme()
+	defer theme.Apply()
+
+	NoColorTheme.Apply()
+
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	var written strings.Builder
+	WriteTopNodes(&written, explain, 2)
+
+	want := "○ Slowest Nodes:\n" +
+		"  1. 475.30 ms (95%) #1 Nested Loop\n" +
+		"  2. 470.00 ms (94%) #3 Nested Loop › Function Scan\n"
+
+	if written.String() != want {
+		t.Errorf("WriteTopNodes =\n%v\nwant\n%v", written.String(), want)
+	}
+}
+
+func TestCalculateCostRanks(t *testing.T) {
+	explain := analyzeOne(t, nestedLoopFunctionScan)
+
+	ranks := []int{explain.Plan.CostRank, explain.Plan.Plans[0].CostRank, explain.Plan.Plans[1].CostRank}
+
+	if ranks[0] != 2 || ranks[1] != 1 || ranks[2] != 3 {
+		t.Errorf("cost ranks = %v, want [2 1 3]", ranks)
+	}
+
+	tied := Explain{Plan: Plan{Plans: []Plan{{}, {}}}}
+	CalculateCostRanks(&tied)
+
+	if tied.Plan.CostRank != 1 || tied.Plan.Plans[0].CostRank != 2 || tied.Plan.Plans[1].CostRank != 3 {
+		t.Errorf("tied cost ranks do not follow plan order")
+	}
+
+	if rendered := renderPlain(t, explain, Options{ShowCostRank: true}); !strings.Contains(rendered, "#2 outer Seq Scan #1 by cost") {
+		t.Errorf("output missing the cost rank:\n%v", rendered)
+	}
+}
+
+func TestCollectIndexes(t *testing.T) {
+	explain := &Explain{Plan: Plan{NodeType: NestedLoop, Plans: []Plan{
+		{NodeType: IndexScan, IndexName: "orders_customer_idx"},
+		{NodeType: BitmapHeapScan, Plans: []Plan{{NodeType: BitmapIndexScan, IndexName: "items_pkey"}}},
+		{NodeType: IndexOnlyScan, IndexName: "orders_customer_idx"},
+		{NodeType: SequenceScan},
+	}}}
+
+	got := CollectIndexes(explain)
+
+	if len(got) != 2 || got[0] != "orders_customer_idx" || got[1] != "items_pkey" {
+		t.Errorf("CollectIndexes = %q, want [orders_customer_idx items_pkey]", got)
+	}
+
+	if got := CollectIndexes(&Explain{Plan: Plan{NodeType: SequenceScan}}); got != nil {
+		t.Errorf("CollectIndexes without indexes = %q, want nil", got)
+	}
+}
@@ -0,0 +1,98 @@
+package gopev
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnknownFields lists the keys in an EXPLAIN (FORMAT JSON) document that the
+// Explain and Plan structs do not model, as paths such as
+// "[0].Plan.Plans[1].Disabled". Parsing stays permissive; this is for
+// finding out what a newer server reports that is being ignored.
+func UnknownFields(buffer []byte) ([]string, error) {
+	var document interface{}
+
+	if err := json.Unmarshal(UnwrapExplain(buffer), &document); err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+
+	collectUnknownFields(document, reflect.TypeOf([]Explain{}), "", &unknown)
+
+	return unknown, nil
+}
+
+func collectUnknownFields(value interface{}, target reflect.Type, path string, unknown *[]string) {
+	for target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+
+		if !ok {
+			return
+		}
+
+		var keys []string
+		for key, _ := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			field, ok := fieldForKey(target, key)
+
+			if !ok {
+				*unknown = append(*unknown, joinFieldPath(path, key))
+				continue
+			}
+
+			collectUnknownFields(object[key], field.Type, joinFieldPath(path, key), unknown)
+		}
+	case reflect.Slice:
+		array, ok := value.([]interface{})
+
+		if !ok {
+			return
+		}
+
+		for index, element := range array {
+			collectUnknownFields(element, target.Elem(), fmt.Sprintf("%v[%d]", path, index), unknown)
+		}
+	}
+}
+
+// fieldForKey finds the field a JSON key decodes into, matching the way
+// encoding/json does: by tag name, or by field name, ignoring case.
+func fieldForKey(target reflect.Type, key string) (reflect.StructField, bool) {
+	for index := 0; index < target.NumField(); index++ {
+		field := target.Field(index)
+		name := field.Name
+
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func joinFieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
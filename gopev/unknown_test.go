@@ -0,0 +1,33 @@
+package gopev
+
+import (
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	unknown, err := UnknownFields([]byte(`[{"Plan": {"Node Type": "Append", "Plan Disabled Nodes": 0, "total cost": 10,
+		"Plans": [{"Node Type": "Seq Scan", "Disabled": true, "Output": ["a"]}]},
+		"Execution Time": 1, "JIT": {"Functions": 3}}]`))
+
+	if err != nil {
+		t.Fatalf("UnknownFields: %v", err)
+	}
+
+	want := []string{"[0].JIT", "[0].Plan.Plan Disabled Nodes", "[0].Plan.Plans[0].Disabled"}
+
+	if len(unknown) != len(want) {
+		t.Fatalf("UnknownFields = %q, want %q", unknown, want)
+	}
+
+	for index := range want {
+		if unknown[index] != want[index] {
+			t.Errorf("UnknownFields[%d] = %q, want %q", index, unknown[index], want[index])
+		}
+	}
+}
+
+func TestUnknownFieldsInvalidJSON(t *testing.T) {
+	if _, err := UnknownFields([]byte(`[{"Plan": `)); err == nil {
+		t.Errorf("UnknownFields of truncated JSON returned no error")
+	}
+}
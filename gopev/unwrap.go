@@ -0,0 +1,95 @@
+package gopev
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// UnwrapExplain finds the EXPLAIN array in a JSON document that wraps it,
+// as the exports of GUI tools such as pgAdmin and DBeaver do, by searching
+// it depth-first in document order for the first array of plans or single
+// plan object. Plans held in a string, as when a result set is exported
+// with the plan as a column value, are searched too. A document with no
+// plan is returned unchanged, so parsing reports the error.
+func UnwrapExplain(buffer []byte) []byte {
+	if found := findExplain(buffer); found != nil {
+		return found
+	}
+
+	return buffer
+}
+
+func findExplain(value []byte) []byte {
+	value = bytes.TrimSpace(value)
+
+	if len(value) == 0 {
+		return nil
+	}
+
+	switch value[0] {
+	case '[':
+		var elements []json.RawMessage
+
+		if json.Unmarshal(value, &elements) != nil {
+			return nil
+		}
+
+		if len(elements) > 0 && isPlanObject(elements[0]) {
+			return value
+		}
+
+		for _, element := range elements {
+			if found := findExplain(element); found != nil {
+				return found
+			}
+		}
+	case '{':
+		if isPlanObject(value) {
+			return append(append([]byte("["), value...), ']')
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(value))
+
+		if _, err := decoder.Token(); err != nil {
+			return nil
+		}
+
+		for decoder.More() {
+			var field json.RawMessage
+
+			if _, err := decoder.Token(); err != nil {
+				return nil
+			}
+
+			if decoder.Decode(&field) != nil {
+				return nil
+			}
+
+			if found := findExplain(field); found != nil {
+				return found
+			}
+		}
+	case '"':
+		var text string
+
+		if json.Unmarshal(value, &text) == nil {
+			return findExplain([]byte(text))
+		}
+	}
+
+	return nil
+}
+
+// isPlanObject reports whether value is an object with a Plan object, the
+// shape of each element of an EXPLAIN (FORMAT JSON) array.
+func isPlanObject(value []byte) bool {
+	var fields map[string]json.RawMessage
+
+	if json.Unmarshal(value, &fields) != nil {
+		return false
+	}
+
+	plan := bytes.TrimSpace(fields["Plan"])
+
+	return len(plan) > 0 && plan[0] == '{'
+}
@@ -0,0 +1,39 @@
+package gopev
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnwrapExplain(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Result"}}]`
+	quoted, _ := json.Marshal(plan)
+
+	tests := []struct {
+		name   string
+		buffer string
+		want   string
+	}{
+		{"plain", plan, plan},
+		{"object", `{"Plan": {"Node Type": "Result"}}`, plan},
+		{"wrapped", `{"query": "SELECT 1", "rows": [{"plan": ` + plan + `}]}`, plan},
+		{"string", `[{"QUERY PLAN": ` + string(quoted) + `}]`, plan},
+		{"first", `{"a": [{"Plan": {"Node Type": "Limit"}}], "b": ` + plan + `}`, `[{"Plan": {"Node Type": "Limit"}}]`},
+		{"none", `{"rows": [1, 2]}`, `{"rows": [1, 2]}`},
+		{"invalid", `{"Plan": `, `{"Plan": `},
+	}
+
+	for _, test := range tests {
+		if got := string(UnwrapExplain([]byte(test.buffer))); got != test.want {
+			t.Errorf("%v: UnwrapExplain = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAnalyzeGUIExports(t *testing.T) {
+	for _, name := range []string{"dbeaver.json", "pgadmin.json"} {
+		if explain := analyzeFile(t, name); explain.Plan.NodeType == "" {
+			t.Errorf("%v: no plan found in the export", name)
+		}
+	}
+}
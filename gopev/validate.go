@@ -0,0 +1,65 @@
+package gopev
+
+import (
+	"fmt"
+	"math"
+)
+
+var ParentRelationships = []string{"Outer", "Inner", "Member", "Subquery", "InitPlan", "SubPlan"}
+
+// ValidatePlan looks for signs of a corrupt or hand-edited plan and returns a
+// description of each anomaly found. It does not need ProcessExplain to have
+// run. Nodes are numbered in pre-order from 1, as AssignIDs does. The
+// children of a node that runs them at the same time are held to the
+// slowest of them, as CalculateActuals does.
+func ValidatePlan(explain *Explain) []string {
+	var anomalies []string
+
+	id := 0
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		id++
+
+		var Anomaly = func(format string, a ...interface{}) {
+			anomalies = append(anomalies, fmt.Sprintf("#%d %v: %v", id, plan.NodeType, fmt.Sprintf(format, a...)))
+		}
+
+		if plan.NodeType == "" {
+			Anomaly("missing node type")
+		}
+
+		if plan.ActualTotalTime < 0 || plan.ActualStartupTime < 0 {
+			Anomaly("negative actual time")
+		}
+
+		if plan.ActualStartupTime > plan.ActualTotalTime {
+			Anomaly("startup time %.3f ms is after total time %.3f ms", plan.ActualStartupTime, plan.ActualTotalTime)
+		}
+
+		if plan.StartupCost > plan.TotalCost {
+			Anomaly("startup cost %.2f exceeds total cost %.2f", plan.StartupCost, plan.TotalCost)
+		}
+
+		if len(path) > 0 && !containsString(ParentRelationships, plan.ParentRelationship) {
+			Anomaly("unknown parent relationship %q", plan.ParentRelationship)
+		}
+
+		var children float64
+
+		for _, child := range plan.Plans {
+			if child.NodeType != CTEScan && child.ParentRelationship != "InitPlan" && child.ParentRelationship != "SubPlan" {
+				if RunsChildrenConcurrently(plan) {
+					children = math.Max(children, child.ActualTotalTime)
+				} else {
+					children += child.ActualTotalTime
+				}
+			}
+		}
+
+		if children > plan.ActualTotalTime*1.01+0.01 {
+			Anomaly("children took %.3f ms but the node only %.3f ms", children, plan.ActualTotalTime)
+		}
+	})
+
+	return anomalies
+}
@@ -0,0 +1,88 @@
+package gopev
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatePlan(t *testing.T) {
+	explain := Explain{Plan: Plan{NodeType: NestedLoop, StartupCost: 20, TotalCost: 10, ActualStartupTime: 1, ActualTotalTime: 5, Plans: []Plan{
+		{NodeType: SequenceScan, ParentRelationship: "Outer", ActualTotalTime: 4},
+		{ParentRelationship: "Sideways", ActualStartupTime: 3, ActualTotalTime: 2},
+	}}}
+
+	want := []string{
+		"#1 Nested Loop: startup cost 20.00 exceeds total cost 10.00",
+		"#1 Nested Loop: children took 6.000 ms but the node only 5.000 ms",
+		"#3 : missing node type",
+		"#3 : startup time 3.000 ms is after total time 2.000 ms",
+		`#3 : unknown parent relationship "Sideways"`,
+	}
+
+	got := ValidatePlan(&explain)
+
+	if len(got) != len(want) {
+		t.Fatalf("ValidatePlan = %q, want %q", got, want)
+	}
+
+	for index := range want {
+		if got[index] != want[index] {
+			t.Errorf("anomaly %d = %q, want %q", index, got[index], want[index])
+		}
+	}
+}
+
+func TestValidatePlanConcurrentChildren(t *testing.T) {
+	members := []Plan{
+		{NodeType: SequenceScan, ParentRelationship: "Member", ActualTotalTime: 25},
+		{NodeType: SequenceScan, ParentRelationship: "Member", ActualTotalTime: 28},
+	}
+
+	parallel := Explain{Plan: Plan{NodeType: Append, ParallelAware: true, ActualTotalTime: 30, Plans: members}}
+
+	if got := ValidatePlan(&parallel); len(got) != 0 {
+		t.Errorf("ValidatePlan(Parallel Append) = %q, want none", got)
+	}
+
+	parallel.Plan.ActualTotalTime = 20
+
+	if got := ValidatePlan(&parallel); len(got) != 1 || got[0] != "#1 Append: children took 28.000 ms but the node only 20.000 ms" {
+		t.Errorf("ValidatePlan(Parallel Append slower child) = %q", got)
+	}
+
+	serial := Explain{Plan: Plan{NodeType: Append, ActualTotalTime: 30, Plans: members}}
+
+	if got := ValidatePlan(&serial); len(got) != 1 || got[0] != "#1 Append: children took 53.000 ms but the node only 30.000 ms" {
+		t.Errorf("ValidatePlan(serial Append) = %q", got)
+	}
+}
+
+func TestValidatePlanTestdata(t *testing.T) {
+	plans, err := filepath.Glob("testdata/*.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plan := range plans {
+		buffer, err := os.ReadFile(plan)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var explains []Explain
+
+		if err := json.Unmarshal(buffer, &explains); err != nil {
+			continue
+		}
+
+		for index := range explains {
+			if anomalies := ValidatePlan(&explains[index]); len(anomalies) > 0 {
+				t.Errorf("%v: %q", plan, anomalies)
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package gopev
+
+import (
+	"fmt"
+)
+
+type WarningKind string
+
+const (
+	BadEstimate     WarningKind = "bad estimate"
+	SlowForCost     WarningKind = "slow for cost"
+	DiskSpill       WarningKind = "disk spill"
+	FilteredSeqScan WarningKind = "seq scan"
+	LowCacheHit     WarningKind = "low cache hit"
+	HintWarning     WarningKind = "hint"
+)
+
+// A Warning is a problem found on one node, for tooling that wants the
+// findings as data rather than as rendered text. NodeIndex is the node's ID.
+type Warning struct {
+	NodeIndex int
+	Kind      WarningKind
+	Severity  Severity
+	Message   string
+}
+
+// TaggedWarnings are the kinds rendered as tags next to the node type. The
+// other kinds are shown as hint lines under the node.
+var TaggedWarnings = []WarningKind{BadEstimate, SlowForCost, FilteredSeqScan, LowCacheHit}
+
+// The factors at which an underestimate or an overestimate of a node's rows
+// counts as a bad estimate. Underestimates tend to do more harm, so they can
+// be held to a stricter threshold.
+var BadUnderestimateFactor float64 = 100
+
+var BadOverestimateFactor float64 = 100
+
+// SlowForCostShare is the smallest share of the plan's total self-time a
+// node must take before it is tagged slow for cost, so that nodes too quick
+// to matter are not flagged for timer noise.
+var SlowForCostShare float64 = 0.05
+
+var SeqScanRemovalRatio float64 = 0.9
+
+var SeqScanRemovedRows uint64 = 1000
+
+var CacheHitRatio float64 = 0.9
+
+var CacheHitBlocks uint64 = 1000
+
+// Warnings lists the warnings for every node of a processed Explain, in
+// pre-order.
+func Warnings(explain *Explain) []Warning {
+	return collectWarnings(explain, Options{})
+}
+
+// collectWarnings is Warnings with the opt-in hints enabled in options.
+func collectWarnings(explain *Explain, options Options) []Warning {
+	var warnings []Warning
+
+	CollectPlans(&explain.Plan, func(plan *Plan, path []*Plan) {
+		warnings = append(warnings, planWarnings(explain, plan, options)...)
+	})
+
+	return warnings
+}
+
+// PlanWarnings lists the warnings for a single node. Hints that are not
+// covered by one of the more specific kinds are reported as HintWarning.
+func PlanWarnings(explain *Explain, plan *Plan) []Warning {
+	return planWarnings(explain, plan, Options{})
+}
+
+func planWarnings(explain *Explain, plan *Plan, options Options) []Warning {
+	warnings := tagWarnings(plan)
+
+	var Add = func(kind WarningKind, message string) {
+		warnings = append(warnings, Warning{NodeIndex: plan.ID, Kind: kind, Severity: SeverityWarning, Message: message})
+	}
+
+	spills := map[string]bool{}
+
+	for _, message := range []string{SortSpillHint(explain, plan), StorageSpillHint(explain, plan), HashAggSpillHint(explain, plan)} {
+		if message != "" {
+			Add(DiskSpill, message)
+			spills[message] = true
+		}
+	}
+
+	for _, message := range planHints(explain, plan, options) {
+		if !spills[message] {
+			Add(HintWarning, message)
+		}
+	}
+
+	return warnings
+}
+
+// ownSharedBlocks returns the shared blocks a node hit and read itself.
+// EXPLAIN counts include the children's, so theirs are taken off, and never
+// below nothing, so a slow read deep in the plan is reported only once.
+func ownSharedBlocks(plan *Plan) (hit, read uint64) {
+	hit, read = plan.SharedHitBlocks, plan.SharedReadBlocks
+
+	var childHit, childRead uint64
+
+	for _, child := range plan.Plans {
+		childHit += child.SharedHitBlocks
+		childRead += child.SharedReadBlocks
+	}
+
+	if childHit < hit {
+		hit -= childHit
+	} else {
+		hit = 0
+	}
+
+	if childRead < read {
+		read -= childRead
+	} else {
+		read = 0
+	}
+
+	return hit, read
+}
+
+// warnedBranches lists plan and the nodes below it that have a warning on
+// them or below them. It looks at each node's warnings once, so WarningsOnly
+// does not walk a branch again for every node in it.
+func warnedBranches(explain *Explain, plan *Plan, options Options) map[*Plan]bool {
+	warned := map[*Plan]bool{}
+
+	markWarnedBranches(explain, plan, options, warned)
+
+	return warned
+}
+
+func markWarnedBranches(explain *Explain, plan *Plan, options Options, warned map[*Plan]bool) bool {
+	found := len(planWarnings(explain, plan, options)) > 0
+
+	for index, _ := range plan.Plans {
+		if markWarnedBranches(explain, &plan.Plans[index], options, warned) {
+			found = true
+		}
+	}
+
+	if found {
+		warned[plan] = true
+	}
+
+	return found
+}
+
+// renderClean draws a branch without warnings as a single line for
+// Options.WarningsOnly.
+func renderClean(explain *Explain, plan *Plan, options Options, prefix string, depth int, lastChild bool, emit func(line PlanLine) bool) bool {
+	nodes := 0
+
+	CollectPlans(plan, func(node *Plan, path []*Plan) {
+		nodes++
+	})
+
+	joint := Glyphs.Branch
+	if lastChild {
+		joint = Glyphs.LastBranch
+	}
+
+	clean := "no warnings"
+	if nodes == 2 {
+		clean = "and 1 node below, no warnings"
+	} else if nodes > 2 {
+		clean = fmt.Sprintf("and %v nodes below, no warnings", nodes-1)
+	}
+
+	summary := fmt.Sprintf("%v %v%v %v", MutedFormat(fmt.Sprintf("#%d", plan.ID)), FormatJoinSide(plan), EstimateFormat(plan)(plan.NodeType), MutedFormat(clean))
+
+	lines := []string{
+		PrefixFormat(prefix + Glyphs.Vertical),
+		PrefixFormat(prefix) + PrefixFormat(nodeConnector(joint, indentWidth(options))) + " " + summary,
+	}
+
+	if options.Style == StyleIndent {
+		lines = []string{PrefixFormat(prefix) + summary}
+	}
+
+	for index, text := range lines {
+		if !emit(PlanLine{Depth: depth, NodeIndex: plan.ID, Text: text, Severity: nodeSeverity(explain, plan, options), Header: index == len(lines)-1}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsBadEstimate reports whether the planner's row estimate for a node was off
+// by at least the threshold for its direction.
+func IsBadEstimate(plan *Plan) bool {
+	switch plan.PlannerRowEstimateDirection {
+	case Under:
+		return plan.PlannerRowEstimateFactor >= BadUnderestimateFactor
+	case Over:
+		return plan.PlannerRowEstimateFactor >= BadOverestimateFactor
+	}
+
+	return false
+}
+
+// tagWarnings are the warnings that can be told from the node alone.
+func tagWarnings(plan *Plan) []Warning {
+	var warnings []Warning
+
+	if IsBadEstimate(plan) {
+		warnings = append(warnings, Warning{
+			NodeIndex: plan.ID,
+			Kind:      BadEstimate,
+			Severity:  SeverityCritical,
+			Message:   fmt.Sprintf("rows %vestimated by %.2fx", plan.PlannerRowEstimateDirection, plan.PlannerRowEstimateFactor),
+		})
+	}
+
+	if plan.CostEstimateDirection == Under && plan.CostEstimateFactor >= 10 {
+		warnings = append(warnings, Warning{
+			NodeIndex: plan.ID,
+			Kind:      SlowForCost,
+			Severity:  SeverityWarning,
+			Message:   fmt.Sprintf("took %.2fx longer than its cost suggests", plan.CostEstimateFactor),
+		})
+	}
+
+	if plan.NodeType == SequenceScan && plan.RowsRemovedByFilter >= SeqScanRemovedRows && FilterRemovalRatio(plan) >= SeqScanRemovalRatio {
+		warnings = append(warnings, Warning{
+			NodeIndex: plan.ID,
+			Kind:      FilteredSeqScan,
+			Severity:  SeverityWarning,
+			Message:   fmt.Sprintf("sequential scan on %v discards %.0f%% of the rows it reads", plan.RelationName, FilterRemovalRatio(plan)*100),
+		})
+	}
+
+	hit, read := ownSharedBlocks(plan)
+
+	if blocks := hit + read; blocks >= CacheHitBlocks {
+		if ratio := float64(hit) / float64(blocks); ratio < CacheHitRatio {
+			warnings = append(warnings, Warning{
+				NodeIndex: plan.ID,
+				Kind:      LowCacheHit,
+				Severity:  SeverityWarning,
+				Message:   fmt.Sprintf("only %.0f%% of %v blocks were found in shared buffers", ratio*100, FormatInteger(int64(blocks))),
+			})
+		}
+	}
+
+	return warnings
+}
+
+func isTagged(kind WarningKind) bool {
+	for _, tagged := range TaggedWarnings {
+		if tagged == kind {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,210 @@
+package gopev
+
+import (
+	"strings"
+	"testing"
+)
+
+func warningKinds(explain *Explain) map[int][]WarningKind {
+	kinds := map[int][]WarningKind{}
+
+	for _, warning := range Warnings(explain) {
+		kinds[warning.NodeIndex] = append(kinds[warning.NodeIndex], warning.Kind)
+	}
+
+	return kinds
+}
+
+func TestLowCacheHitOnlyOnReadingNode(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Aggregate", "Strategy": "Plain", "Total Cost": 200, "Plan Rows": 1, "Actual Total Time": 50, "Actual Rows": 1, "Actual Loops": 1, "Shared Hit Blocks": 120, "Shared Read Blocks": 9000,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Total Cost": 100, "Plan Rows": 1000, "Actual Total Time": 40, "Actual Rows": 1000, "Actual Loops": 1, "Shared Hit Blocks": 100, "Shared Read Blocks": 9000}]},
+		"Execution Time": 50}]`)
+
+	kinds := warningKinds(explain)
+
+	if len(kinds[1]) != 0 {
+		t.Errorf("aggregate warnings = %v, want none", kinds[1])
+	}
+
+	if len(kinds[2]) != 1 || kinds[2][0] != LowCacheHit {
+		t.Errorf("seq scan warnings = %v, want [%v]", kinds[2], LowCacheHit)
+	}
+}
+
+func TestOwnSharedBlocks(t *testing.T) {
+	plan := &Plan{SharedHitBlocks: 50, SharedReadBlocks: 30, Plans: []Plan{
+		{SharedHitBlocks: 20, SharedReadBlocks: 10},
+		{SharedHitBlocks: 40, SharedReadBlocks: 5},
+	}}
+
+	hit, read := ownSharedBlocks(plan)
+
+	if hit != 0 || read != 15 {
+		t.Errorf("ownSharedBlocks = %v, %v, want 0, 15", hit, read)
+	}
+}
+
+func TestPlanWarningsKinds(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Sort", "Sort Key": ["t.a"], "Sort Method": "external merge", "Sort Space Used": 20000, "Sort Space Type": "Disk", "Total Cost": 100, "Plan Rows": 10, "Actual Total Time": 60, "Actual Rows": 100000, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "t", "Schema": "public", "Filter": "(b = 1)", "Rows Removed by Filter": 900000, "Total Cost": 90, "Plan Rows": 10, "Actual Total Time": 30, "Actual Rows": 100000, "Actual Loops": 1}]},
+		"Execution Time": 60}]`)
+
+	kinds := warningKinds(explain)
+
+	want := map[int][]WarningKind{
+		1: {BadEstimate, DiskSpill},
+		2: {BadEstimate, FilteredSeqScan},
+	}
+
+	for id, expected := range want {
+		for _, kind := range expected {
+			found := false
+
+			for _, got := range kinds[id] {
+				found = found || got == kind
+			}
+
+			if !found {
+				t.Errorf("node #%d warnings = %v, missing %v", id, kinds[id], kind)
+			}
+		}
+	}
+}
+
+func TestIsBadEstimateThresholds(t *testing.T) {
+	tests := []struct {
+		direction EstimateDirection
+		factor    float64
+		want      bool
+	}{
+		{Under, 99, false},
+		{Under, 100, true},
+		{Over, 150, true},
+		{Over, 2, false},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{PlannerRowEstimateDirection: test.direction, PlannerRowEstimateFactor: test.factor}
+
+		if got := IsBadEstimate(plan); got != test.want {
+			t.Errorf("IsBadEstimate(%v %v) = %v, want %v", test.direction, test.factor, got, test.want)
+		}
+	}
+}
+
+func TestSeparateEstimateThresholds(t *testing.T) {
+	under, over := BadUnderestimateFactor, BadOverestimateFactor
+	defer func() { BadUnderestimateFactor, BadOverestimateFactor = under, over }()
+
+	BadUnderestimateFactor, BadOverestimateFactor = 10, 1000
+
+	tests := []struct {
+		direction EstimateDirection
+		factor    float64
+		want      bool
+	}{
+		{Under, 10, true},
+		{Under, 9, false},
+		{Over, 500, false},
+		{Over, 1000, true},
+	}
+
+	for _, test := range tests {
+		plan := &Plan{PlannerRowEstimateDirection: test.direction, PlannerRowEstimateFactor: test.factor}
+
+		if got := IsBadEstimate(plan); got != test.want {
+			t.Errorf("IsBadEstimate(%v %v) with thresholds 10/1000 = %v, want %v", test.direction, test.factor, got, test.want)
+		}
+	}
+}
+
+func TestWarningsOnly(t *testing.T) {
+	explain := analyzeOne(t, `[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Hash Cond": "(a.id = b.id)", "Total Cost": 100, "Plan Rows": 10, "Actual Total Time": 5, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "a", "Filter": "(x = 1)", "Total Cost": 40, "Plan Rows": 1, "Actual Total Time": 2, "Actual Rows": 1000, "Actual Loops": 1},
+			{"Node Type": "Hash", "Parent Relationship": "Inner", "Total Cost": 50, "Plan Rows": 10, "Actual Total Time": 2, "Actual Rows": 10, "Actual Loops": 1,
+				"Plans": [{"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "b", "Total Cost": 40, "Plan Rows": 10, "Actual Total Time": 1, "Actual Rows": 10, "Actual Loops": 1}]}
+		]}, "Execution Time": 5}]`)
+
+	warned := warnedBranches(explain, &explain.Plan, Options{})
+
+	if warned[&explain.Plan.Plans[1]] || warned[&explain.Plan.Plans[1].Plans[0]] {
+		t.Errorf("Hash branch has warnings: %v", warningKinds(explain))
+	}
+
+	if !warned[&explain.Plan] || !warned[&explain.Plan.Plans[0]] {
+		t.Errorf("branches with warnings not found: %v", warned)
+	}
+
+	out := renderPlain(t, explain, Options{WarningsOnly: true})
+
+	if !strings.Contains(out, "#3 inner Hash and 1 node below, no warnings") {
+		t.Errorf("clean branch is not folded:\n%v", out)
+	}
+
+	if strings.Contains(out, "#4") {
+		t.Errorf("folded branch still draws its children:\n%v", out)
+	}
+
+	if !strings.Contains(out, "#2 ") || !strings.Contains(out, "filter (x = 1)") {
+		t.Errorf("branch with warnings is not drawn in full:\n%v", out)
+	}
+
+	indented := renderPlain(t, explain, Options{WarningsOnly: true, Style: StyleIndent})
+
+	if !strings.Contains(indented, "\n  #3 ") {
+		t.Errorf("folded branch is not indented:\n%v", indented)
+	}
+}
+
+// TestCountedWarningsAreShown checks that every warning the footer counts
+// shows up on its node, as a tag or as a hint line.
+func TestCountedWarningsAreShown(t *testing.T) {
+	for _, name := range []string{"seqscan.json", "nestedloop.json", "hashagg.json", "singlecopy.json", "skewed.json"} {
+		explain := analyzeFile(t, name)
+		rendered := renderPlain(t, explain, Options{})
+
+		for _, warning := range Warnings(explain) {
+			plan := NodeByID(explain, warning.NodeIndex)
+			tagged := false
+
+			for _, tag := range PlanTags(plan) {
+				tagged = tagged || tag == string(warning.Kind)
+			}
+
+			if !tagged && !strings.Contains(rendered, warning.Message) {
+				t.Errorf("%v: #%d %v warning %q is counted but not shown:\n%v", name, warning.NodeIndex, warning.Kind, warning.Message, rendered)
+			}
+		}
+	}
+
+	rendered := renderPlain(t, analyzeFile(t, "seqscan.json"), Options{})
+
+	if !strings.Contains(rendered, "#1 Seq Scan [ slowest ] [ costliest ] [ largest ] [ seq scan ]") {
+		t.Errorf("filtered seq scan is not tagged:\n%v", rendered)
+	}
+}
+
+func TestWarnedBranchesDeepPlan(t *testing.T) {
+	explain := nested(300)
+	leaf := &explain.Plan
+
+	for len(leaf.Plans) > 0 {
+		leaf = &leaf.Plans[0]
+	}
+
+	leaf.PlanRows, leaf.ActualRows, leaf.ActualLoops = 1, 1000, 1
+	ProcessExplain(&explain)
+
+	warned := warnedBranches(&explain, &explain.Plan, Options{})
+
+	if len(warned) != 301 {
+		t.Errorf("warnedBranches found %d nodes above the bad estimate, want 301", len(warned))
+	}
+
+	rendered := renderPlain(t, &explain, Options{WarningsOnly: true, MaxDepth: 400})
+
+	if strings.Contains(rendered, "no warnings ") || !strings.Contains(rendered, "#301 Materialize") {
+		t.Errorf("branch down to the bad estimate folded:\n%v", rendered)
+	}
+}
@@ -0,0 +1,33 @@
+package gopev
+
+import (
+	"github.com/mattn/go-runewidth"
+	"strings"
+)
+
+// wrapText breaks text at spaces into lines of at most limit terminal
+// cells, measuring wide characters such as CJK as two cells. Words longer
+// than limit are left whole.
+func wrapText(text string, limit int) string {
+	var lines []string
+	line, width := "", 0
+
+	for _, word := range strings.Fields(text) {
+		size := runewidth.StringWidth(word)
+
+		if width > 0 && width+1+size > limit {
+			lines = append(lines, line)
+			line, width = "", 0
+		}
+
+		if width > 0 {
+			line += " "
+			width++
+		}
+
+		line += word
+		width += size
+	}
+
+	return strings.Join(append(lines, line), "\n")
+}
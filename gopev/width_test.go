@@ -0,0 +1,40 @@
+package gopev
+
+import (
+	"github.com/mattn/go-runewidth"
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"one two three four", 9, "one two\nthree\nfour"},
+		{"  spaced   out  ", 20, "spaced out"},
+		{"unbreakableword ok", 5, "unbreakableword\nok"},
+		{"", 10, ""},
+	}
+
+	for _, test := range tests {
+		if got := wrapText(test.text, test.limit); got != test.want {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", test.text, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestWrapTextWideCharacters(t *testing.T) {
+	text := strings.Repeat("表格 ", 20)
+
+	for _, line := range strings.Split(wrapText(text, 12), "\n") {
+		if width := runewidth.StringWidth(line); width > 12 {
+			t.Errorf("line %q is %d cells wide, want at most 12", line, width)
+		}
+	}
+
+	if got := wrapText("表格 表格 表格", 10); got != "表格 表格\n表格" {
+		t.Errorf("wrapText of wide words = %q, want two per line", got)
+	}
+}
@@ -3,6 +3,7 @@ package main
 
 import (
   "./gopev"
+  "flag"
   "io/ioutil"
   "github.com/fatih/color"
   "log"
@@ -10,6 +11,51 @@ import (
 )
 
 func main() {
+  var options gopev.Options
+  var stream bool
+  var colorBlind bool
+  var strict bool
+  var ascii bool
+  var lint bool
+
+  flag.IntVar(&options.TopNodes, "top", 0, "list the N slowest nodes after the tree")
+  flag.BoolVar(&options.ShowPlannerCost, "costs", false, "show the planner's cost range for each node")
+  flag.BoolVar(&options.Redact, "redact", false, "replace literals in conditions and output with ?")
+  flag.BoolVar(&options.ShowLegend, "legend", false, "explain the tags and colors after the tree")
+  flag.BoolVar(&options.Triage, "triage", false, "only show the details that matter most for finding a slow node")
+  flag.BoolVar(&options.WarningsOnly, "warnings-only", false, "fold branches without warnings into a single line")
+  flag.IntVar(&options.MaxLines, "max-lines", 0, "stop after N lines of output")
+  flag.BoolVar(&stream, "stream", false, "read several concatenated JSON plans")
+  flag.BoolVar(&colorBlind, "color-blind", false, "use a palette that does not rely on red and green")
+  flag.BoolVar(&lint, "lint", false, "only list the problems found, exiting with status 1 if there are any")
+  flag.BoolVar(&strict, "strict", false, "list plan fields that are not understood")
+  flag.Uint64Var(&options.WorkMemKB, "work-mem", 0, "warn about nodes likely to need more than this many kB of work_mem")
+  flag.Float64Var(&options.HashMemMultiplier, "hash-mem-multiplier", gopev.DefaultHashMemMultiplier, "hash_mem_multiplier applied to -work-mem for hash joins and hash aggregates")
+  flag.IntVar(&options.IndentWidth, "indent", gopev.DefaultIndentWidth, "indent each level of the tree by N columns, at least 2")
+  flag.StringVar(&options.Style, "style", gopev.StyleTree, "tree, or indent for plain indentation")
+  flag.BoolVar(&ascii, "ascii", false, "draw the tree with ASCII characters only")
+  flag.BoolVar(&options.CastHint, "cast-hint", false, "hint at columns cast in conditions, which may prevent index use")
+  flag.BoolVar(&options.SerialPlanHint, "serial-hint", false, "hint at expensive plans over large scans that did not run in parallel")
+  flag.Parse()
+
+  if ascii {
+    options.Symbols = &gopev.ASCIISymbols
+  }
+
+  if colorBlind {
+    options.Theme = &gopev.ColorBlindTheme
+  }
+
+  if stream {
+    err := gopev.VisualizeStream(color.Output, os.Stdin, options)
+
+    if err != nil {
+      log.Fatalf("%v", err)
+    }
+
+    return
+  }
+
   buffer, err := ioutil.ReadAll(os.Stdin)
 
   if err != nil {
@@ -18,7 +64,33 @@ func main() {
 
   // fmt.Println(string(buffer))
 
-  err = gopev.Visualize(color.Output, buffer)
+  if strict {
+    unknown, err := gopev.UnknownFields(buffer)
+
+    if err != nil {
+      log.Fatalf("%v", err)
+    }
+
+    for _, field := range unknown {
+      log.Printf("unknown field %v", field)
+    }
+  }
+
+  if lint {
+    problems, err := gopev.LintWithOptions(color.Output, buffer, options)
+
+    if err != nil {
+      log.Fatalf("%v", err)
+    }
+
+    if problems > 0 {
+      os.Exit(1)
+    }
+
+    return
+  }
+
+  err = gopev.VisualizeWithOptions(color.Output, buffer, options)
 
   if err != nil {
     log.Fatalf("%v", err)